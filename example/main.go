@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -49,24 +47,14 @@ func main() {
 		skop.WithLogger(logger),
 	)
 
-	runErrCh := make(chan error, 1)
-	go func() {
-		runErrCh <- op.Run()
-	}()
-
-	sigCh := make(chan os.Signal)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	mgr := skop.NewManager(
+		skop.WithManagerLogger(logger),
+	)
+	mgr.Add(op)
 
-	select {
-	case sig := <-sigCh:
-		level.Info(logger).Log(
-			"msg", "received signal",
-			"signal", sig,
-		)
-		op.Stop()
-	case err := <-runErrCh:
+	if err := mgr.Run(); err != nil {
 		level.Error(logger).Log(
-			"msg", "operator failed",
+			"msg", "manager failed",
 			"err", err,
 		)
 		os.Exit(1)
@@ -125,7 +113,16 @@ func reconciler(ctx context.Context, op *skop.Operator, res skop.Resource) error
 		},
 	}
 
-	return reconcile.Deployment(ctx, op.Clientset(), deployment)
+	_, outcome, err := reconcile.Deployment(ctx, op.Clientset(), deployment)
+	if err != nil {
+		return err
+	}
+	skop.Logger(ctx).Log(
+		"msg", "reconciled deployment",
+		"resource", test.Name,
+		"outcome", outcome,
+	)
+	return nil
 }
 
 func makeLogger() log.Logger {
@@ -0,0 +1,124 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// DefaultFieldManager is the field manager used by Apply
+// unless the FieldManager option is specified.
+const DefaultFieldManager = "skop"
+
+type applyOptions struct {
+	fieldManager string
+	force        bool
+}
+
+type ApplyOption func(o *applyOptions)
+
+// FieldManager sets the name of the field manager owning the applied fields.
+func FieldManager(name string) ApplyOption {
+	return func(o *applyOptions) {
+		o.fieldManager = name
+	}
+}
+
+// ForceConflicts makes Apply take ownership of fields
+// which are owned by other field managers.
+func ForceConflicts() ApplyOption {
+	return func(o *applyOptions) {
+		o.force = true
+	}
+}
+
+// Apply creates or updates obj using server-side apply. Unlike the other reconcile
+// helpers, it only sets the fields specified in obj and leaves fields owned by other
+// field managers untouched. Apply supports the kinds the other helpers support
+// and returns the object returned by the API server. The outcome is derived from
+// whether the object existed and whether applying it changed its resource version.
+func Apply(ctx context.Context, cs kubernetes.Interface, obj runtime.Object, options ...ApplyOption) (runtime.Object, Outcome, error) {
+	o := applyOptions{
+		fieldManager: DefaultFieldManager,
+	}
+	for _, option := range options {
+		option(&o)
+	}
+
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	desired := obj.DeepCopyObject()
+	desired.GetObjectKind().SetGroupVersionKind(gvks[0])
+	data, err := json.Marshal(desired)
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	opts := metav1.PatchOptions{
+		FieldManager: o.fieldManager,
+		Force:        &o.force,
+	}
+
+	var get, patch func() (metav1.Object, error)
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		client := cs.CoreV1().ConfigMaps(obj.Namespace)
+		get = func() (metav1.Object, error) { return client.Get(ctx, obj.Name, metav1.GetOptions{}) }
+		patch = func() (metav1.Object, error) { return client.Patch(ctx, obj.Name, types.ApplyPatchType, data, opts) }
+	case *appsv1.DaemonSet:
+		client := cs.AppsV1().DaemonSets(obj.Namespace)
+		get = func() (metav1.Object, error) { return client.Get(ctx, obj.Name, metav1.GetOptions{}) }
+		patch = func() (metav1.Object, error) { return client.Patch(ctx, obj.Name, types.ApplyPatchType, data, opts) }
+	case *appsv1.Deployment:
+		client := cs.AppsV1().Deployments(obj.Namespace)
+		get = func() (metav1.Object, error) { return client.Get(ctx, obj.Name, metav1.GetOptions{}) }
+		patch = func() (metav1.Object, error) { return client.Patch(ctx, obj.Name, types.ApplyPatchType, data, opts) }
+	case *batchv1.Job:
+		client := cs.BatchV1().Jobs(obj.Namespace)
+		get = func() (metav1.Object, error) { return client.Get(ctx, obj.Name, metav1.GetOptions{}) }
+		patch = func() (metav1.Object, error) { return client.Patch(ctx, obj.Name, types.ApplyPatchType, data, opts) }
+	case *corev1.PersistentVolumeClaim:
+		client := cs.CoreV1().PersistentVolumeClaims(obj.Namespace)
+		get = func() (metav1.Object, error) { return client.Get(ctx, obj.Name, metav1.GetOptions{}) }
+		patch = func() (metav1.Object, error) { return client.Patch(ctx, obj.Name, types.ApplyPatchType, data, opts) }
+	case *corev1.Service:
+		client := cs.CoreV1().Services(obj.Namespace)
+		get = func() (metav1.Object, error) { return client.Get(ctx, obj.Name, metav1.GetOptions{}) }
+		patch = func() (metav1.Object, error) { return client.Patch(ctx, obj.Name, types.ApplyPatchType, data, opts) }
+	case *appsv1.StatefulSet:
+		client := cs.AppsV1().StatefulSets(obj.Namespace)
+		get = func() (metav1.Object, error) { return client.Get(ctx, obj.Name, metav1.GetOptions{}) }
+		patch = func() (metav1.Object, error) { return client.Patch(ctx, obj.Name, types.ApplyPatchType, data, opts) }
+	default:
+		return nil, Unchanged, fmt.Errorf("reconcile: apply not supported for %s", gvks[0].Kind)
+	}
+
+	existing, err := get()
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, Unchanged, err
+	}
+	exists := err == nil
+	applied, err := patch()
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	switch {
+	case !exists:
+		return applied.(runtime.Object), Created, nil
+	case applied.GetResourceVersion() != existing.GetResourceVersion():
+		return applied.(runtime.Object), Updated, nil
+	default:
+		return applied.(runtime.Object), Unchanged, nil
+	}
+}
@@ -0,0 +1,140 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	var patch k8stesting.PatchAction
+	cs.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch = action.(k8stesting.PatchAction)
+		return true, &corev1.ConfigMap{}, nil
+	})
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Data: map[string]string{
+			"key": "value",
+		},
+	}
+	_, outcome, err := Apply(ctx, cs, configMap, FieldManager("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome != Created {
+		t.Fatalf("unexpected outcome: %s", outcome)
+	}
+
+	if patch == nil {
+		t.Fatal("expected patch")
+	}
+	if patch.GetPatchType() != types.ApplyPatchType || patch.GetName() != "test" || patch.GetNamespace() != "skop" {
+		t.Fatalf("unexpected patch: %+v", patch)
+	}
+	var applied corev1.ConfigMap
+	if err := json.Unmarshal(patch.GetPatch(), &applied); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied.APIVersion != "v1" || applied.Kind != "ConfigMap" || applied.Data["key"] != "value" {
+		t.Fatalf("unexpected applied object: %+v", applied)
+	}
+	if configMap.Kind != "" {
+		t.Fatal("desired object must not be modified")
+	}
+}
+
+func TestApplyOutcome(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Namespace:       "skop",
+			ResourceVersion: "1",
+		},
+	})
+
+	// The fake clientset does not support server-side apply.
+	resourceVersion := "1"
+	cs.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test",
+				Namespace:       "skop",
+				ResourceVersion: resourceVersion,
+			},
+		}, nil
+	})
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	}
+	if _, outcome, err := Apply(ctx, cs, configMap); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %s, %v", outcome, err)
+	}
+
+	resourceVersion = "2"
+	if _, outcome, err := Apply(ctx, cs, configMap); err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %s, %v", outcome, err)
+	}
+}
+
+func TestApplyDeploymentWithoutReplicas(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	var patch k8stesting.PatchAction
+	cs.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch = action.(k8stesting.PatchAction)
+		return true, &appsv1.Deployment{}, nil
+	})
+
+	// Replicas are managed by a HorizontalPodAutoscaler and must not be applied.
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "test"},
+			},
+		},
+	}
+	if _, _, err := Apply(ctx, cs, deployment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if patch == nil {
+		t.Fatal("expected patch")
+	}
+	if strings.Contains(string(patch.GetPatch()), "replicas") {
+		t.Fatalf("unexpected replicas in patch: %s", patch.GetPatch())
+	}
+	var applied appsv1.Deployment
+	if err := json.Unmarshal(patch.GetPatch(), &applied); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied.Kind != "Deployment" || applied.Spec.Selector.MatchLabels["app"] != "test" {
+		t.Fatalf("unexpected applied object: %+v", applied)
+	}
+}
@@ -9,26 +9,41 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func ConfigMap(ctx context.Context, cs *kubernetes.Clientset, configMap *corev1.ConfigMap) error {
+func ConfigMap(ctx context.Context, cs kubernetes.Interface, configMap *corev1.ConfigMap) (*corev1.ConfigMap, Outcome, error) {
+	configMap = configMap.DeepCopy()
+	h, err := setHash(configMap, configMap.Labels, configMap.Annotations, configMap.Data, configMap.BinaryData, configMap.Immutable)
+	if err != nil {
+		return nil, Unchanged, err
+	}
 	client := cs.CoreV1().ConfigMaps(configMap.Namespace)
 	existing, err := client.Get(ctx, configMap.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			_, err = client.Create(ctx, configMap, metav1.CreateOptions{})
-			return err
+			created, err := client.Create(ctx, configMap, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
 		}
-		return err
+		return nil, Unchanged, err
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
 	}
+	before := existing.DeepCopy()
 	existing.Labels = configMap.Labels
 	existing.Annotations = configMap.Annotations
 	existing.Data = configMap.Data
 	existing.BinaryData = configMap.BinaryData
 	existing.Immutable = configMap.Immutable
-	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
-	return err
+	updated, err := client.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
 }
 
-func ConfigMapAbsence(ctx context.Context, cs *kubernetes.Clientset, configMap *corev1.ConfigMap) error {
+func ConfigMapAbsence(ctx context.Context, cs kubernetes.Interface, configMap *corev1.ConfigMap) error {
 	return Absence(func() error {
 		return cs.CoreV1().ConfigMaps(configMap.Namespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{})
 	})
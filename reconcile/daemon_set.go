@@ -9,24 +9,39 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func DaemonSet(ctx context.Context, cs *kubernetes.Clientset, daemonSet *appsv1.DaemonSet) error {
+func DaemonSet(ctx context.Context, cs kubernetes.Interface, daemonSet *appsv1.DaemonSet) (*appsv1.DaemonSet, Outcome, error) {
+	daemonSet = daemonSet.DeepCopy()
+	h, err := setHash(daemonSet, daemonSet.Labels, daemonSet.Annotations, daemonSet.Spec)
+	if err != nil {
+		return nil, Unchanged, err
+	}
 	client := cs.AppsV1().DaemonSets(daemonSet.Namespace)
 	existing, err := client.Get(ctx, daemonSet.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			_, err = client.Create(ctx, daemonSet, metav1.CreateOptions{})
-			return err
+			created, err := client.Create(ctx, daemonSet, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
 		}
-		return err
+		return nil, Unchanged, err
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
 	}
+	before := existing.DeepCopy()
 	existing.Labels = daemonSet.Labels
 	existing.Annotations = daemonSet.Annotations
 	existing.Spec = daemonSet.Spec
-	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
-	return err
+	updated, err := client.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
 }
 
-func DaemonSetAbsence(ctx context.Context, cs *kubernetes.Clientset, daemonSet *appsv1.DaemonSet) error {
+func DaemonSetAbsence(ctx context.Context, cs kubernetes.Interface, daemonSet *appsv1.DaemonSet) error {
 	return Absence(func() error {
 		propagationPolicy := metav1.DeletePropagationBackground
 		return cs.AppsV1().DaemonSets(daemonSet.Namespace).Delete(ctx, daemonSet.Name, metav1.DeleteOptions{
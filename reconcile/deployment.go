@@ -9,24 +9,39 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func Deployment(ctx context.Context, cs *kubernetes.Clientset, deployment *appsv1.Deployment) error {
+func Deployment(ctx context.Context, cs kubernetes.Interface, deployment *appsv1.Deployment) (*appsv1.Deployment, Outcome, error) {
+	deployment = deployment.DeepCopy()
+	h, err := setHash(deployment, deployment.Labels, deployment.Annotations, deployment.Spec)
+	if err != nil {
+		return nil, Unchanged, err
+	}
 	client := cs.AppsV1().Deployments(deployment.Namespace)
 	existing, err := client.Get(ctx, deployment.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			_, err = client.Create(ctx, deployment, metav1.CreateOptions{})
-			return err
+			created, err := client.Create(ctx, deployment, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
 		}
-		return err
+		return nil, Unchanged, err
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
 	}
+	before := existing.DeepCopy()
 	existing.Labels = deployment.Labels
 	existing.Annotations = deployment.Annotations
 	existing.Spec = deployment.Spec
-	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
-	return err
+	updated, err := client.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
 }
 
-func DeploymentAbsence(ctx context.Context, cs *kubernetes.Clientset, deployment *appsv1.Deployment) error {
+func DeploymentAbsence(ctx context.Context, cs kubernetes.Interface, deployment *appsv1.Deployment) error {
 	return Absence(func() error {
 		propagationPolicy := metav1.DeletePropagationBackground
 		return cs.AppsV1().Deployments(deployment.Namespace).Delete(ctx, deployment.Name, metav1.DeleteOptions{
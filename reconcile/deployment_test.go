@@ -0,0 +1,82 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeployment(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+		},
+	}
+	if _, outcome, err := Deployment(ctx, cs, deployment); err != nil || outcome != Created {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if _, outcome, err := Deployment(ctx, cs, deployment); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	deployment.Spec.Replicas = int32Ptr(2)
+	updated, outcome, err := Deployment(ctx, cs, deployment)
+	if err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if *updated.Spec.Replicas != 2 {
+		t.Fatalf("unexpected replicas in returned object: %d", *updated.Spec.Replicas)
+	}
+
+	existing, err := cs.AppsV1().Deployments("skop").Get(ctx, "test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *existing.Spec.Replicas != 2 {
+		t.Fatalf("unexpected replicas: %d", *existing.Spec.Replicas)
+	}
+
+	if err := DeploymentAbsence(ctx, cs, deployment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := DeploymentAbsence(ctx, cs, deployment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestDeploymentSkipsUnchanged(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := Deployment(ctx, cs, deployment); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for _, action := range cs.Actions() {
+		if action.GetVerb() == "update" {
+			t.Fatalf("unexpected update")
+		}
+	}
+	if len(deployment.Annotations) != 0 {
+		t.Fatalf("desired deployment must not be modified")
+	}
+}
@@ -0,0 +1,38 @@
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ImmutableFieldError is returned by reconcile helpers when the desired state
+// of an object differs from the existing object in fields which cannot be updated.
+type ImmutableFieldError struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Fields lists the paths of the fields which block the update,
+	// for example spec.serviceName.
+	Fields []string
+}
+
+func (e *ImmutableFieldError) Error() string {
+	return fmt.Sprintf("reconcile: cannot update immutable fields of %s %s/%s: %s",
+		e.Kind, e.Namespace, e.Name, strings.Join(e.Fields, ", "))
+}
+
+// ShrinkError is returned by PersistentVolumeClaim when the desired
+// storage request is less than the request of the existing claim.
+type ShrinkError struct {
+	Namespace string
+	Name      string
+	Current   resource.Quantity
+	Requested resource.Quantity
+}
+
+func (e *ShrinkError) Error() string {
+	return fmt.Sprintf("reconcile: cannot shrink persistent volume claim %s/%s from %s to %s",
+		e.Namespace, e.Name, e.Current.String(), e.Requested.String())
+}
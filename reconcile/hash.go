@@ -0,0 +1,53 @@
+package reconcile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HashAnnotation is the annotation in which reconcile helpers store a hash of
+// the desired state they last applied to an object. When the hash of the desired
+// state matches the annotation of the existing object, the object is not updated.
+// As a consequence, changes made to an object by others are only reverted when
+// the desired state changes or the annotation is removed.
+const HashAnnotation = "skop.io/last-applied-hash"
+
+// hash returns a hash of the JSON representation of the specified values.
+// It fails when the values cannot be encoded as JSON.
+func hash(values ...interface{}) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// setHash computes the hash of the desired state described by values and
+// stores it in the hash annotation of obj.
+func setHash(obj metav1.Object, values ...interface{}) (string, error) {
+	h, err := hash(values...)
+	if err != nil {
+		return "", err
+	}
+	setAnnotation(obj, HashAnnotation, h)
+	return h, nil
+}
+
+// hasHash reports whether obj was last updated to the desired state with the specified hash.
+func hasHash(obj metav1.Object, h string) bool {
+	return obj.GetAnnotations()[HashAnnotation] == h
+}
+
+// setAnnotation sets an annotation of obj without modifying its existing annotations map.
+func setAnnotation(obj metav1.Object, key, value string) {
+	annotations := make(map[string]string, len(obj.GetAnnotations())+1)
+	for k, v := range obj.GetAnnotations() {
+		annotations[k] = v
+	}
+	annotations[key] = value
+	obj.SetAnnotations(annotations)
+}
@@ -2,30 +2,136 @@ package reconcile
 
 import (
 	"context"
+	"fmt"
 
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-func Job(ctx context.Context, cs *kubernetes.Clientset, job *batchv1.Job) error {
+// TemplateHashAnnotation is the annotation in which Job stores a hash of the
+// pod template a job was created with.
+const TemplateHashAnnotation = "skop.io/template-hash"
+
+// JobPolicy determines what Job does with an existing job
+// whose pod template differs from the desired one.
+type JobPolicy int
+
+const (
+	// IgnoreTemplateChanges keeps the existing job and only updates
+	// its labels and annotations.
+	IgnoreTemplateChanges JobPolicy = iota
+	// RecreateJob deletes the existing job, stopping its pods,
+	// and creates it again.
+	RecreateJob
+	// RecreateFinishedJob waits until the existing job has succeeded
+	// or failed before deleting and creating it again.
+	RecreateFinishedJob
+)
+
+type jobOptions struct {
+	policy JobPolicy
+}
+
+type JobOption func(o *jobOptions)
+
+// WithJobPolicy sets the policy for existing jobs whose pod template
+// differs from the desired one. The default is IgnoreTemplateChanges.
+func WithJobPolicy(policy JobPolicy) JobOption {
+	return func(o *jobOptions) {
+		o.policy = policy
+	}
+}
+
+// Job creates a job or updates the labels and annotations of an existing job.
+// As the pod template of a job cannot be updated, a changed template is handled
+// according to the job policy. Jobs created without the template hash annotation
+// are adopted: their template is assumed to be the desired one and the annotation
+// is added. Use JobPhaseOf to inspect the completion state of the returned job.
+func Job(ctx context.Context, cs kubernetes.Interface, job *batchv1.Job, options ...JobOption) (*batchv1.Job, Outcome, error) {
+	var o jobOptions
+	for _, option := range options {
+		option(&o)
+	}
+
+	job = job.DeepCopy()
+	th, err := hash(job.Spec.Template)
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	setAnnotation(job, TemplateHashAnnotation, th)
+	h, err := setHash(job, job.Labels, job.Annotations)
+	if err != nil {
+		return nil, Unchanged, err
+	}
 	client := cs.BatchV1().Jobs(job.Namespace)
 	existing, err := client.Get(ctx, job.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			_, err = client.Create(ctx, job, metav1.CreateOptions{})
-			return err
+			created, err := client.Create(ctx, job, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
 		}
-		return err
+		return nil, Unchanged, err
 	}
+	existingTemplateHash, ok := existing.Annotations[TemplateHashAnnotation]
+	if ok && existingTemplateHash != th {
+		switch o.policy {
+		case RecreateJob:
+			return recreateJob(ctx, cs, job, existing)
+		case RecreateFinishedJob:
+			if phase := JobPhaseOf(existing); phase == JobSucceeded || phase == JobFailed {
+				return recreateJob(ctx, cs, job, existing)
+			}
+		}
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
+	}
+	before := existing.DeepCopy()
 	existing.Labels = job.Labels
 	existing.Annotations = job.Annotations
-	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
-	return err
+	if ok {
+		// The template hash annotation must keep describing the existing
+		// job's pod template, which is not updated.
+		existing.Annotations[TemplateHashAnnotation] = existingTemplateHash
+	}
+	updated, err := client.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
 }
 
-func JobAbsence(ctx context.Context, cs *kubernetes.Clientset, job *batchv1.Job) error {
+func recreateJob(ctx context.Context, cs kubernetes.Interface, job, existing *batchv1.Job) (*batchv1.Job, Outcome, error) {
+	client := cs.BatchV1().Jobs(job.Namespace)
+	if existing.DeletionTimestamp == nil {
+		propagationPolicy := metav1.DeletePropagationBackground
+		err := client.Delete(ctx, existing.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagationPolicy,
+			Preconditions: &metav1.Preconditions{
+				UID: &existing.UID,
+			},
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, Unchanged, err
+		}
+	}
+	created, err := client.Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			return nil, Unchanged, fmt.Errorf("reconcile: job %s/%s is still being deleted", job.Namespace, job.Name)
+		}
+		return nil, Unchanged, err
+	}
+	return created, Recreated, nil
+}
+
+func JobAbsence(ctx context.Context, cs kubernetes.Interface, job *batchv1.Job) error {
 	return Absence(func() error {
 		propagationPolicy := metav1.DeletePropagationBackground
 		return cs.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, metav1.DeleteOptions{
@@ -33,3 +139,36 @@ func JobAbsence(ctx context.Context, cs *kubernetes.Clientset, job *batchv1.Job)
 		})
 	})
 }
+
+// JobPhase describes the completion state of a job.
+type JobPhase string
+
+const (
+	// JobPending means none of the job's pods are running yet.
+	JobPending JobPhase = "Pending"
+	// JobRunning means the job has active pods.
+	JobRunning JobPhase = "Running"
+	// JobSucceeded means the job has completed.
+	JobSucceeded JobPhase = "Succeeded"
+	// JobFailed means the job has failed and will not be retried.
+	JobFailed JobPhase = "Failed"
+)
+
+// JobPhaseOf returns the phase of job based on its status.
+func JobPhaseOf(job *batchv1.Job) JobPhase {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return JobSucceeded
+		case batchv1.JobFailed:
+			return JobFailed
+		}
+	}
+	if job.Status.Active > 0 {
+		return JobRunning
+	}
+	return JobPending
+}
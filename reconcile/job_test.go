@@ -0,0 +1,189 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestJob() *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "test", Image: "alpine:3.12"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func setJobStatus(t *testing.T, cs *fake.Clientset, name string, status batchv1.JobStatus) {
+	ctx := context.Background()
+	job, err := cs.BatchV1().Jobs("skop").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job.Status = status
+	if _, err := cs.BatchV1().Jobs("skop").UpdateStatus(ctx, job, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestJobIgnoresTemplateChanges(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	job := newTestJob()
+	if _, outcome, err := Job(ctx, cs, job); err != nil || outcome != Created {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	job.Spec.Template.Spec.Containers[0].Image = "alpine:3.13"
+	job.Labels = map[string]string{"app": "test"}
+	updated, outcome, err := Job(ctx, cs, job)
+	if err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if image := updated.Spec.Template.Spec.Containers[0].Image; image != "alpine:3.12" {
+		t.Fatalf("unexpected image: %s", image)
+	}
+	if updated.Labels["app"] != "test" {
+		t.Fatalf("unexpected labels: %v", updated.Labels)
+	}
+	if _, outcome, err := Job(ctx, cs, job); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	// Switching to a recreate policy recreates the job as the
+	// template hash still describes the original template.
+	recreated, outcome, err := Job(ctx, cs, job, WithJobPolicy(RecreateJob))
+	if err != nil || outcome != Recreated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if image := recreated.Spec.Template.Spec.Containers[0].Image; image != "alpine:3.13" {
+		t.Fatalf("unexpected image: %s", image)
+	}
+}
+
+func TestJobRecreate(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	job := newTestJob()
+	if _, _, err := Job(ctx, cs, job, WithJobPolicy(RecreateJob)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, outcome, err := Job(ctx, cs, job, WithJobPolicy(RecreateJob)); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	job.Spec.Template.Spec.Containers[0].Image = "alpine:3.13"
+	recreated, outcome, err := Job(ctx, cs, job, WithJobPolicy(RecreateJob))
+	if err != nil || outcome != Recreated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if image := recreated.Spec.Template.Spec.Containers[0].Image; image != "alpine:3.13" {
+		t.Fatalf("unexpected image: %s", image)
+	}
+}
+
+func TestJobRecreateFinished(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	job := newTestJob()
+	if _, _, err := Job(ctx, cs, job, WithJobPolicy(RecreateFinishedJob)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	setJobStatus(t, cs, "test", batchv1.JobStatus{Active: 1})
+
+	// While the job is running, only its labels and annotations are updated.
+	job.Spec.Template.Spec.Containers[0].Image = "alpine:3.13"
+	job.Labels = map[string]string{"app": "test"}
+	existing, outcome, err := Job(ctx, cs, job, WithJobPolicy(RecreateFinishedJob))
+	if err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if phase := JobPhaseOf(existing); phase != JobRunning {
+		t.Fatalf("unexpected phase: %s", phase)
+	}
+	if existing.Labels["app"] != "test" {
+		t.Fatalf("unexpected labels: %v", existing.Labels)
+	}
+	if image := existing.Spec.Template.Spec.Containers[0].Image; image != "alpine:3.12" {
+		t.Fatalf("unexpected image: %s", image)
+	}
+	if _, outcome, err := Job(ctx, cs, job, WithJobPolicy(RecreateFinishedJob)); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	setJobStatus(t, cs, "test", batchv1.JobStatus{
+		Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+		},
+	})
+	recreated, outcome, err := Job(ctx, cs, job, WithJobPolicy(RecreateFinishedJob))
+	if err != nil || outcome != Recreated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if phase := JobPhaseOf(recreated); phase != JobPending {
+		t.Fatalf("unexpected phase: %s", phase)
+	}
+}
+
+func TestJobAdoptsJobWithoutTemplateHash(t *testing.T) {
+	ctx := context.Background()
+	existing := newTestJob()
+	existing.Status.Active = 1
+	cs := fake.NewSimpleClientset(existing)
+
+	adopted, outcome, err := Job(ctx, cs, newTestJob(), WithJobPolicy(RecreateJob))
+	if err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if adopted.Annotations[TemplateHashAnnotation] == "" {
+		t.Fatal("expected template hash annotation")
+	}
+	if phase := JobPhaseOf(adopted); phase != JobRunning {
+		t.Fatalf("unexpected phase: %s", phase)
+	}
+	if _, outcome, err := Job(ctx, cs, newTestJob(), WithJobPolicy(RecreateJob)); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+}
+
+func TestJobPhaseOf(t *testing.T) {
+	tests := []struct {
+		status batchv1.JobStatus
+		phase  JobPhase
+	}{
+		{batchv1.JobStatus{}, JobPending},
+		{batchv1.JobStatus{Active: 1}, JobRunning},
+		{batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		}, JobSucceeded},
+		{batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+		}, JobFailed},
+		{batchv1.JobStatus{
+			Active:     1,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionFalse}},
+		}, JobRunning},
+	}
+	for _, test := range tests {
+		if phase := JobPhaseOf(&batchv1.Job{Status: test.status}); phase != test.phase {
+			t.Errorf("unexpected phase for %+v: got %s, want %s", test.status, phase, test.phase)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package reconcile
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Object creates or updates obj, which is either an *unstructured.Unstructured
+// or a typed object known to client-go's scheme, using a dynamic client. The
+// mapper resolves the object's kind to a resource, so any kind including custom
+// resources of other operators is supported. The labels, annotations, and all
+// top-level fields except metadata and status of an existing object are replaced
+// by those of obj; fields missing from obj are removed.
+func Object(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, obj runtime.Object) (*unstructured.Unstructured, Outcome, error) {
+	desired, err := toUnstructured(obj)
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	ri, err := resourceInterface(client, mapper, desired)
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	h, err := setHash(desired, desired.GetLabels(), desired.GetAnnotations(), content(desired))
+	if err != nil {
+		return nil, Unchanged, err
+	}
+
+	existing, err := ri.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			created, err := ri.Create(ctx, desired, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
+		}
+		return nil, Unchanged, err
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
+	}
+	before := existing.DeepCopy()
+	existing.SetLabels(desired.GetLabels())
+	existing.SetAnnotations(desired.GetAnnotations())
+	for k := range content(existing) {
+		delete(existing.Object, k)
+	}
+	for k, v := range content(desired) {
+		existing.Object[k] = v
+	}
+	updated, err := ri.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
+}
+
+func ObjectAbsence(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, obj runtime.Object) error {
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	ri, err := resourceInterface(client, mapper, u)
+	if err != nil {
+		return err
+	}
+	return Absence(func() error {
+		propagationPolicy := metav1.DeletePropagationBackground
+		return ri.Delete(ctx, u.GetName(), metav1.DeleteOptions{
+			PropagationPolicy: &propagationPolicy,
+		})
+	})
+}
+
+// toUnstructured returns a copy of obj as *unstructured.Unstructured
+// with its apiVersion and kind set.
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.DeepCopy(), nil
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			return nil, err
+		}
+		gvk = gvks[0]
+	}
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: data}
+	u.SetGroupVersionKind(gvk)
+	return u, nil
+}
+
+func resourceInterface(client dynamic.Interface, mapper meta.RESTMapper, u *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := u.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return client.Resource(mapping.Resource).Namespace(u.GetNamespace()), nil
+	}
+	return client.Resource(mapping.Resource), nil
+}
+
+// content returns the top-level fields of u except
+// apiVersion, kind, metadata, and status.
+func content(u *unstructured.Unstructured) map[string]interface{} {
+	c := make(map[string]interface{}, len(u.Object))
+	for k, v := range u.Object {
+		switch k {
+		case "apiVersion", "kind", "metadata", "status":
+		default:
+			c[k] = v
+		}
+	}
+	return c
+}
@@ -0,0 +1,110 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+func newTestRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(certificateGVK, meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	return mapper
+}
+
+func TestObject(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	mapper := newTestRESTMapper()
+
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"secretName": "test-tls",
+			"dnsNames":   []interface{}{"example.com"},
+		},
+	}}
+	certificate.SetGroupVersionKind(certificateGVK)
+	certificate.SetNamespace("skop")
+	certificate.SetName("test")
+
+	if _, outcome, err := Object(ctx, client, mapper, certificate); err != nil || outcome != Created {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if _, outcome, err := Object(ctx, client, mapper, certificate); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	certificate.Object["spec"].(map[string]interface{})["secretName"] = "other-tls"
+	updated, outcome, err := Object(ctx, client, mapper, certificate)
+	if err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if name, _, _ := unstructured.NestedString(updated.Object, "spec", "secretName"); name != "other-tls" {
+		t.Fatalf("unexpected secret name: %q", name)
+	}
+
+	if err := ObjectAbsence(ctx, client, mapper, certificate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ObjectAbsence(ctx, client, mapper, certificate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestObjectTyped(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	mapper := newTestRESTMapper()
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Data: map[string]string{
+			"key": "value",
+		},
+	}
+	if _, outcome, err := Object(ctx, client, mapper, configMap); err != nil || outcome != Created {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	configMap.Data["key"] = "other"
+	if _, outcome, err := Object(ctx, client, mapper, configMap); err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	existing, err := client.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace("skop").Get(ctx, "test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _, _ := unstructured.NestedString(existing.Object, "data", "key"); value != "other" {
+		t.Fatalf("unexpected value: %q", value)
+	}
+	if configMap.Annotations != nil {
+		t.Fatalf("desired object was modified: %v", configMap.Annotations)
+	}
+
+	// Fields omitted from the desired object are removed.
+	configMap.Data = nil
+	if _, outcome, err := Object(ctx, client, mapper, configMap); err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	existing, err = client.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace("skop").Get(ctx, "test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := existing.Object["data"]; ok {
+		t.Fatalf("unexpected data: %v", existing.Object["data"])
+	}
+}
@@ -0,0 +1,46 @@
+package reconcile
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Outcome describes how a reconcile helper changed an object.
+type Outcome int
+
+const (
+	// Unchanged means the object already was in the desired state.
+	Unchanged Outcome = iota
+	// Created means the object did not exist and was created.
+	Created
+	// Updated means the existing object was updated.
+	Updated
+	// Recreated means the existing object was deleted and created again
+	// because the desired state could not be reached by an update.
+	Recreated
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Unchanged:
+		return "Unchanged"
+	case Created:
+		return "Created"
+	case Updated:
+		return "Updated"
+	case Recreated:
+		return "Recreated"
+	default:
+		return "Unknown"
+	}
+}
+
+// updateOutcome compares the object before an update with the object returned
+// by the API server. As the API server does not persist updates which do not
+// change an object, an unchanged object means the update was a no-op.
+func updateOutcome(before, after runtime.Object) Outcome {
+	if equality.Semantic.DeepEqual(before, after) {
+		return Unchanged
+	}
+	return Updated
+}
@@ -4,29 +4,97 @@ import (
 	"context"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-func PersistentVolumeClaim(ctx context.Context, cs *kubernetes.Clientset, pvc *corev1.PersistentVolumeClaim) error {
+// PersistentVolumeClaim creates or updates a persistent volume claim. Only
+// the labels, annotations, and resources of an existing claim are updated.
+// As volumes can only be expanded, PersistentVolumeClaim returns a *ShrinkError
+// when the requested storage is less than the existing claim's request. When the
+// storage class, access modes, or volume mode differ from the existing claim,
+// it returns an *ImmutableFieldError. Use FileSystemResizePending to find out
+// whether the expansion of the returned claim waits for its pod to be restarted.
+func PersistentVolumeClaim(ctx context.Context, cs kubernetes.Interface, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, Outcome, error) {
+	pvc = pvc.DeepCopy()
+	h, err := setHash(pvc, pvc.Labels, pvc.Annotations, pvc.Spec)
+	if err != nil {
+		return nil, Unchanged, err
+	}
 	client := cs.CoreV1().PersistentVolumeClaims(pvc.Namespace)
 	existing, err := client.Get(ctx, pvc.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			_, err = client.Create(ctx, pvc, metav1.CreateOptions{})
-			return err
+			created, err := client.Create(ctx, pvc, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
+		}
+		return nil, Unchanged, err
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
+	}
+	if fields := pvcImmutableChanges(pvc, existing); len(fields) > 0 {
+		return nil, Unchanged, &ImmutableFieldError{
+			Kind:      "PersistentVolumeClaim",
+			Namespace: existing.Namespace,
+			Name:      existing.Name,
+			Fields:    fields,
 		}
-		return err
 	}
+	current := existing.Spec.Resources.Requests[corev1.ResourceStorage]
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if requested.Cmp(current) < 0 {
+		return nil, Unchanged, &ShrinkError{
+			Namespace: existing.Namespace,
+			Name:      existing.Name,
+			Current:   current,
+			Requested: requested,
+		}
+	}
+	before := existing.DeepCopy()
 	existing.Labels = pvc.Labels
 	existing.Annotations = pvc.Annotations
 	existing.Spec.Resources = pvc.Spec.Resources
-	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
-	return err
+	updated, err := client.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
+}
+
+// pvcImmutableChanges returns the paths of the immutable fields of existing
+// which are set in desired and differ from it.
+func pvcImmutableChanges(desired, existing *corev1.PersistentVolumeClaim) []string {
+	var fields []string
+	if desired.Spec.StorageClassName != nil && !equality.Semantic.DeepEqual(desired.Spec.StorageClassName, existing.Spec.StorageClassName) {
+		fields = append(fields, "spec.storageClassName")
+	}
+	if len(desired.Spec.AccessModes) > 0 && !equality.Semantic.DeepEqual(desired.Spec.AccessModes, existing.Spec.AccessModes) {
+		fields = append(fields, "spec.accessModes")
+	}
+	if desired.Spec.VolumeMode != nil && !equality.Semantic.DeepEqual(desired.Spec.VolumeMode, existing.Spec.VolumeMode) {
+		fields = append(fields, "spec.volumeMode")
+	}
+	return fields
+}
+
+// FileSystemResizePending reports whether the volume of pvc has been expanded
+// but its file system is only resized when a pod using the claim is started.
+func FileSystemResizePending(pvc *corev1.PersistentVolumeClaim) bool {
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == corev1.PersistentVolumeClaimFileSystemResizePending {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
 }
 
-func PersistentVolumeClaimAbsence(ctx context.Context, cs *kubernetes.Clientset, pvc *corev1.PersistentVolumeClaim) error {
+func PersistentVolumeClaimAbsence(ctx context.Context, cs kubernetes.Interface, pvc *corev1.PersistentVolumeClaim) error {
 	return Absence(func() error {
 		return cs.CoreV1().PersistentVolumeClaims(pvc.Namespace).Delete(ctx, pvc.Name, metav1.DeleteOptions{})
 	})
@@ -0,0 +1,87 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestPVC(storage string) *corev1.PersistentVolumeClaim {
+	storageClassName := "standard"
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClassName,
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(storage),
+				},
+			},
+		},
+	}
+}
+
+func TestPersistentVolumeClaim(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	if _, outcome, err := PersistentVolumeClaim(ctx, cs, newTestPVC("1Gi")); err != nil || outcome != Created {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if _, outcome, err := PersistentVolumeClaim(ctx, cs, newTestPVC("1Gi")); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	updated, outcome, err := PersistentVolumeClaim(ctx, cs, newTestPVC("2Gi"))
+	if err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if storage := updated.Spec.Resources.Requests[corev1.ResourceStorage]; storage.String() != "2Gi" {
+		t.Fatalf("unexpected storage: %s", storage.String())
+	}
+
+	_, _, err = PersistentVolumeClaim(ctx, cs, newTestPVC("1Gi"))
+	var shrinkErr *ShrinkError
+	if !errors.As(err, &shrinkErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if shrinkErr.Current.String() != "2Gi" || shrinkErr.Requested.String() != "1Gi" {
+		t.Fatalf("unexpected error: %v", shrinkErr)
+	}
+
+	pvc := newTestPVC("2Gi")
+	storageClassName := "fast"
+	pvc.Spec.StorageClassName = &storageClassName
+	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+	_, _, err = PersistentVolumeClaim(ctx, cs, pvc)
+	var immutableErr *ImmutableFieldError
+	if !errors.As(err, &immutableErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"spec.storageClassName", "spec.accessModes"}; !reflect.DeepEqual(immutableErr.Fields, want) {
+		t.Fatalf("unexpected fields: %v", immutableErr.Fields)
+	}
+}
+
+func TestFileSystemResizePending(t *testing.T) {
+	pvc := newTestPVC("1Gi")
+	if FileSystemResizePending(pvc) {
+		t.Fatal("expected no pending resize")
+	}
+	pvc.Status.Conditions = []corev1.PersistentVolumeClaimCondition{
+		{Type: corev1.PersistentVolumeClaimFileSystemResizePending, Status: corev1.ConditionTrue},
+	}
+	if !FileSystemResizePending(pvc) {
+		t.Fatal("expected pending resize")
+	}
+}
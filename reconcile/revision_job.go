@@ -0,0 +1,98 @@
+package reconcile
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// JobNameLabel is the label in which RevisionJob stores the
+	// name of the job all revisions were derived from.
+	JobNameLabel = "skop.io/job"
+	// JobRevisionLabel is the label in which RevisionJob stores
+	// the revision of a job.
+	JobRevisionLabel = "skop.io/revision"
+)
+
+// GenerationRevision returns a revision which changes
+// whenever the spec of obj changes.
+func GenerationRevision(obj metav1.Object) string {
+	return strconv.FormatInt(obj.GetGeneration(), 10)
+}
+
+// SpecRevision returns a revision which changes whenever spec changes.
+// It fails when spec cannot be encoded as JSON.
+func SpecRevision(spec interface{}) (string, error) {
+	h, err := hash(spec)
+	if err != nil {
+		return "", err
+	}
+	return h[:10], nil
+}
+
+// RevisionJob runs job once per revision. The job is created with its name
+// suffixed by the revision unless it already exists, so a job runs again
+// only when the revision changes. Jobs of other revisions are deleted, except
+// for the keep-1 most recently created ones. RevisionJob returns the job of the
+// current revision and its phase. As the job's name is used as a label value
+// for its pods, the suffixed name must not be longer than 63 characters.
+func RevisionJob(ctx context.Context, cs kubernetes.Interface, job *batchv1.Job, revision string, keep int) (*batchv1.Job, JobPhase, error) {
+	name := job.Name
+	job = job.DeepCopy()
+	job.Name = name + "-" + revision
+	jobLabels := make(map[string]string, len(job.Labels)+2)
+	for k, v := range job.Labels {
+		jobLabels[k] = v
+	}
+	jobLabels[JobNameLabel] = name
+	jobLabels[JobRevisionLabel] = revision
+	job.Labels = jobLabels
+
+	current, _, err := Job(ctx, cs, job)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := pruneRevisionJobs(ctx, cs, job.Namespace, name, job.Name, keep); err != nil {
+		return nil, "", err
+	}
+	return current, JobPhaseOf(current), nil
+}
+
+// pruneRevisionJobs deletes the jobs derived from name except for
+// the current job and the keep-1 most recently created other jobs.
+func pruneRevisionJobs(ctx context.Context, cs kubernetes.Interface, namespace, name, current string, keep int) error {
+	list, err := cs.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{JobNameLabel: name}).String(),
+	})
+	if err != nil {
+		return err
+	}
+	var jobs []batchv1.Job
+	for _, job := range list.Items {
+		if job.Name != current {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		ti, tj := jobs[i].CreationTimestamp, jobs[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return tj.Before(&ti)
+		}
+		return jobs[i].Name > jobs[j].Name
+	})
+	if keep < 1 {
+		keep = 1
+	}
+	for i := keep - 1; i < len(jobs); i++ {
+		if err := JobAbsence(ctx, cs, &jobs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
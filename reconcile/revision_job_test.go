@@ -0,0 +1,89 @@
+package reconcile
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRevisionJob(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	// Jobs of older revisions; the fake clientset does not
+	// set the creation timestamp of created jobs.
+	now := time.Now()
+	for i, revision := range []string{"1", "2", "3"} {
+		_, err := cs.BatchV1().Jobs("skop").Create(ctx, &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "test-" + revision,
+				Namespace:         "skop",
+				CreationTimestamp: metav1.NewTime(now.Add(time.Duration(i) * time.Minute)),
+				Labels: map[string]string{
+					JobNameLabel:     "test",
+					JobRevisionLabel: revision,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	job := newTestJob()
+	current, phase, err := RevisionJob(ctx, cs, job, "4", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if current.Name != "test-4" || current.Labels[JobRevisionLabel] != "4" {
+		t.Fatalf("unexpected job: %s %v", current.Name, current.Labels)
+	}
+	if phase != JobPending {
+		t.Fatalf("unexpected phase: %s", phase)
+	}
+	if job.Name != "test" || job.Labels != nil {
+		t.Fatalf("desired job was modified: %s %v", job.Name, job.Labels)
+	}
+
+	list, err := cs.BatchV1().Jobs("skop").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, job := range list.Items {
+		names = append(names, job.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "test-3" || names[1] != "test-4" {
+		t.Fatalf("unexpected jobs: %v", names)
+	}
+
+	setJobStatus(t, cs, "test-4", batchv1.JobStatus{Active: 1})
+	if _, phase, err := RevisionJob(ctx, cs, job, "4", 2); err != nil || phase != JobRunning {
+		t.Fatalf("unexpected result: %v, %v", phase, err)
+	}
+}
+
+func TestSpecRevision(t *testing.T) {
+	spec := map[string]interface{}{"image": "alpine:3.12"}
+	revision, err := SpecRevision(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revision) != 10 {
+		t.Fatalf("unexpected revision: %q", revision)
+	}
+	if other, _ := SpecRevision(spec); other != revision {
+		t.Fatalf("revision not stable: %q != %q", other, revision)
+	}
+
+	if _, err := SpecRevision(map[string]interface{}{"ratio": math.NaN()}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -9,26 +9,101 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func Service(ctx context.Context, cs *kubernetes.Clientset, service *corev1.Service) error {
+// Service creates or updates a service. Fields allocated by the API server,
+// which are the cluster IP, IP family, node ports, and health check node port,
+// are preserved unless they are set in the desired service or do not apply to
+// its type.
+func Service(ctx context.Context, cs kubernetes.Interface, service *corev1.Service) (*corev1.Service, Outcome, error) {
+	service = service.DeepCopy()
+	h, err := setHash(service, service.Labels, service.Annotations, service.Spec)
+	if err != nil {
+		return nil, Unchanged, err
+	}
 	client := cs.CoreV1().Services(service.Namespace)
 	existing, err := client.Get(ctx, service.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			_, err = client.Create(ctx, service, metav1.CreateOptions{})
-			return err
+			created, err := client.Create(ctx, service, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
 		}
-		return err
+		return nil, Unchanged, err
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
 	}
-	clusterIP := existing.Spec.ClusterIP
+	before := existing.DeepCopy()
 	existing.Labels = service.Labels
 	existing.Annotations = service.Annotations
-	existing.Spec = service.Spec
-	existing.Spec.ClusterIP = clusterIP
-	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
-	return err
+	existing.Spec = serviceSpec(service.Spec, existing.Spec)
+	updated, err := client.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
+}
+
+// serviceSpec returns the desired spec with the allocated fields of the existing
+// spec filled in. The clusterIPs and ipFamilies fields of newer API versions are
+// not known to this client and are kept by the API server when clusterIP is unchanged.
+func serviceSpec(desired, existing corev1.ServiceSpec) corev1.ServiceSpec {
+	spec := desired
+	if spec.Type == corev1.ServiceTypeExternalName {
+		return spec
+	}
+	if spec.ClusterIP == "" {
+		spec.ClusterIP = existing.ClusterIP
+	}
+	if spec.IPFamily == nil {
+		spec.IPFamily = existing.IPFamily
+	}
+	if spec.Type != corev1.ServiceTypeNodePort && spec.Type != corev1.ServiceTypeLoadBalancer {
+		return spec
+	}
+	for i := range spec.Ports {
+		port := &spec.Ports[i]
+		if port.NodePort != 0 {
+			continue
+		}
+		if existingPort := findServicePort(existing.Ports, *port); existingPort != nil {
+			port.NodePort = existingPort.NodePort
+		}
+	}
+	if spec.Type == corev1.ServiceTypeLoadBalancer &&
+		spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyTypeLocal &&
+		spec.HealthCheckNodePort == 0 {
+		spec.HealthCheckNodePort = existing.HealthCheckNodePort
+	}
+	return spec
+}
+
+// findServicePort returns the port of ports which corresponds to port, matching
+// named ports by name and unnamed ports by port number and protocol.
+func findServicePort(ports []corev1.ServicePort, port corev1.ServicePort) *corev1.ServicePort {
+	for i, p := range ports {
+		if port.Name != "" {
+			if p.Name == port.Name {
+				return &ports[i]
+			}
+			continue
+		}
+		if p.Port == port.Port && servicePortProtocol(p) == servicePortProtocol(port) {
+			return &ports[i]
+		}
+	}
+	return nil
+}
+
+func servicePortProtocol(port corev1.ServicePort) corev1.Protocol {
+	if port.Protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return port.Protocol
 }
 
-func ServiceAbsence(ctx context.Context, cs *kubernetes.Clientset, service *corev1.Service) error {
+func ServiceAbsence(ctx context.Context, cs kubernetes.Interface, service *corev1.Service) error {
 	return Absence(func() error {
 		return cs.CoreV1().Services(service.Namespace).Delete(ctx, service.Name, metav1.DeleteOptions{})
 	})
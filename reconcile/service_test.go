@@ -0,0 +1,204 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServicePreservesAllocatedFields(t *testing.T) {
+	ipv4 := corev1.IPv4Protocol
+
+	tests := []struct {
+		name     string
+		existing corev1.ServiceSpec
+		desired  corev1.ServiceSpec
+		want     corev1.ServiceSpec
+	}{
+		{
+			name: "ClusterIP",
+			existing: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeClusterIP,
+				ClusterIP: "10.0.0.1",
+				IPFamily:  &ipv4,
+				Ports:     []corev1.ServicePort{{Port: 80}},
+			},
+			desired: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeClusterIP,
+				Ports: []corev1.ServicePort{{Port: 8080}},
+			},
+			want: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeClusterIP,
+				ClusterIP: "10.0.0.1",
+				IPFamily:  &ipv4,
+				Ports:     []corev1.ServicePort{{Port: 8080}},
+			},
+		},
+		{
+			name: "NodePort",
+			existing: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeNodePort,
+				ClusterIP: "10.0.0.1",
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, NodePort: 30080},
+					{Name: "https", Port: 443, NodePort: 30443},
+				},
+			},
+			desired: corev1.ServiceSpec{
+				Type: corev1.ServiceTypeNodePort,
+				Ports: []corev1.ServicePort{
+					{Name: "https", Port: 8443},
+					{Name: "http", Port: 80, NodePort: 31080},
+					{Name: "metrics", Port: 9090},
+				},
+			},
+			want: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeNodePort,
+				ClusterIP: "10.0.0.1",
+				Ports: []corev1.ServicePort{
+					{Name: "https", Port: 8443, NodePort: 30443},
+					{Name: "http", Port: 80, NodePort: 31080},
+					{Name: "metrics", Port: 9090},
+				},
+			},
+		},
+		{
+			name: "NodePortUnnamed",
+			existing: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeNodePort,
+				ClusterIP: "10.0.0.1",
+				Ports: []corev1.ServicePort{
+					{Port: 53, Protocol: corev1.ProtocolUDP, NodePort: 30053},
+				},
+			},
+			desired: corev1.ServiceSpec{
+				Type: corev1.ServiceTypeNodePort,
+				Ports: []corev1.ServicePort{
+					{Port: 53, Protocol: corev1.ProtocolUDP},
+				},
+			},
+			want: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeNodePort,
+				ClusterIP: "10.0.0.1",
+				Ports: []corev1.ServicePort{
+					{Port: 53, Protocol: corev1.ProtocolUDP, NodePort: 30053},
+				},
+			},
+		},
+		{
+			name: "LoadBalancer",
+			existing: corev1.ServiceSpec{
+				Type:                  corev1.ServiceTypeLoadBalancer,
+				ClusterIP:             "10.0.0.1",
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+				HealthCheckNodePort:   32000,
+				Ports:                 []corev1.ServicePort{{Port: 80, NodePort: 30080}},
+			},
+			desired: corev1.ServiceSpec{
+				Type:                  corev1.ServiceTypeLoadBalancer,
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+				Ports:                 []corev1.ServicePort{{Port: 80}},
+			},
+			want: corev1.ServiceSpec{
+				Type:                  corev1.ServiceTypeLoadBalancer,
+				ClusterIP:             "10.0.0.1",
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+				HealthCheckNodePort:   32000,
+				Ports:                 []corev1.ServicePort{{Port: 80, NodePort: 30080}},
+			},
+		},
+		{
+			name: "LoadBalancerToClusterIP",
+			existing: corev1.ServiceSpec{
+				Type:                  corev1.ServiceTypeLoadBalancer,
+				ClusterIP:             "10.0.0.1",
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+				HealthCheckNodePort:   32000,
+				Ports:                 []corev1.ServicePort{{Port: 80, NodePort: 30080}},
+			},
+			desired: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeClusterIP,
+				Ports: []corev1.ServicePort{{Port: 80}},
+			},
+			want: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeClusterIP,
+				ClusterIP: "10.0.0.1",
+				Ports:     []corev1.ServicePort{{Port: 80}},
+			},
+		},
+		{
+			name: "ExternalName",
+			existing: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeClusterIP,
+				ClusterIP: "10.0.0.1",
+				IPFamily:  &ipv4,
+			},
+			desired: corev1.ServiceSpec{
+				Type:         corev1.ServiceTypeExternalName,
+				ExternalName: "example.com",
+			},
+			want: corev1.ServiceSpec{
+				Type:         corev1.ServiceTypeExternalName,
+				ExternalName: "example.com",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			cs := fake.NewSimpleClientset(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "skop",
+				},
+				Spec: test.existing,
+			})
+
+			updated, outcome, err := Service(ctx, cs, &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "skop",
+				},
+				Spec: test.desired,
+			})
+			if err != nil || outcome != Updated {
+				t.Fatalf("unexpected result: %v, %v", outcome, err)
+			}
+			if !equality.Semantic.DeepEqual(updated.Spec, test.want) {
+				t.Fatalf("unexpected spec:\ngot:  %+v\nwant: %+v", updated.Spec, test.want)
+			}
+		})
+	}
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80}},
+		},
+	}
+	if _, outcome, err := Service(ctx, cs, service); err != nil || outcome != Created {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if _, outcome, err := Service(ctx, cs, service); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if err := ServiceAbsence(ctx, cs, service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ServiceAbsence(ctx, cs, service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type statefulSetOptions struct {
+	recreate bool
+}
+
+type StatefulSetOption func(o *statefulSetOptions)
+
+// RecreateOnImmutableChange makes StatefulSet delete an existing stateful set
+// whose immutable fields differ from the desired state and create it again.
+// The stateful set is deleted with orphan propagation so its pods and
+// persistent volume claims are kept and adopted by the new stateful set.
+func RecreateOnImmutableChange() StatefulSetOption {
+	return func(o *statefulSetOptions) {
+		o.recreate = true
+	}
+}
+
+// StatefulSet creates or updates a stateful set. Only the mutable fields replicas,
+// template, and updateStrategy of an existing stateful set are updated. When other
+// fields of the spec differ, StatefulSet returns an *ImmutableFieldError unless the
+// RecreateOnImmutableChange option is specified.
+func StatefulSet(ctx context.Context, cs kubernetes.Interface, statefulSet *appsv1.StatefulSet, options ...StatefulSetOption) (*appsv1.StatefulSet, Outcome, error) {
+	var o statefulSetOptions
+	for _, option := range options {
+		option(&o)
+	}
+
+	statefulSet = statefulSet.DeepCopy()
+	h, err := setHash(statefulSet, statefulSet.Labels, statefulSet.Annotations, statefulSet.Spec)
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	client := cs.AppsV1().StatefulSets(statefulSet.Namespace)
+	existing, err := client.Get(ctx, statefulSet.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			created, err := client.Create(ctx, statefulSet, metav1.CreateOptions{})
+			if err != nil {
+				return nil, Unchanged, err
+			}
+			return created, Created, nil
+		}
+		return nil, Unchanged, err
+	}
+	if hasHash(existing, h) {
+		return existing, Unchanged, nil
+	}
+	if fields := statefulSetImmutableChanges(statefulSet, existing); len(fields) > 0 {
+		if !o.recreate {
+			return nil, Unchanged, &ImmutableFieldError{
+				Kind:      "StatefulSet",
+				Namespace: existing.Namespace,
+				Name:      existing.Name,
+				Fields:    fields,
+			}
+		}
+		return recreateStatefulSet(ctx, cs, statefulSet, existing)
+	}
+	before := existing.DeepCopy()
+	existing.Labels = statefulSet.Labels
+	existing.Annotations = statefulSet.Annotations
+	existing.Spec.Replicas = statefulSet.Spec.Replicas
+	existing.Spec.Template = statefulSet.Spec.Template
+	existing.Spec.UpdateStrategy = statefulSet.Spec.UpdateStrategy
+	updated, err := client.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, Unchanged, err
+	}
+	return updated, updateOutcome(before, updated), nil
+}
+
+func recreateStatefulSet(ctx context.Context, cs kubernetes.Interface, statefulSet, existing *appsv1.StatefulSet) (*appsv1.StatefulSet, Outcome, error) {
+	client := cs.AppsV1().StatefulSets(statefulSet.Namespace)
+	if existing.DeletionTimestamp == nil {
+		propagationPolicy := metav1.DeletePropagationOrphan
+		err := client.Delete(ctx, existing.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagationPolicy,
+			Preconditions: &metav1.Preconditions{
+				UID: &existing.UID,
+			},
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, Unchanged, err
+		}
+	}
+	created, err := client.Create(ctx, statefulSet, metav1.CreateOptions{})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			return nil, Unchanged, fmt.Errorf("reconcile: stateful set %s/%s is still being deleted", statefulSet.Namespace, statefulSet.Name)
+		}
+		return nil, Unchanged, err
+	}
+	return created, Recreated, nil
+}
+
+// statefulSetImmutableChanges returns the paths of the immutable fields of
+// existing which differ from desired. Fields which are defaulted by the API
+// server are only compared when they are set in desired.
+func statefulSetImmutableChanges(desired, existing *appsv1.StatefulSet) []string {
+	var fields []string
+	if !equality.Semantic.DeepEqual(desired.Spec.Selector, existing.Spec.Selector) {
+		fields = append(fields, "spec.selector")
+	}
+	if desired.Spec.ServiceName != existing.Spec.ServiceName {
+		fields = append(fields, "spec.serviceName")
+	}
+	if !claimTemplatesEqual(desired.Spec.VolumeClaimTemplates, existing.Spec.VolumeClaimTemplates) {
+		fields = append(fields, "spec.volumeClaimTemplates")
+	}
+	if desired.Spec.PodManagementPolicy != "" && desired.Spec.PodManagementPolicy != existing.Spec.PodManagementPolicy {
+		fields = append(fields, "spec.podManagementPolicy")
+	}
+	if desired.Spec.RevisionHistoryLimit != nil && !equality.Semantic.DeepEqual(desired.Spec.RevisionHistoryLimit, existing.Spec.RevisionHistoryLimit) {
+		fields = append(fields, "spec.revisionHistoryLimit")
+	}
+	return fields
+}
+
+func claimTemplatesEqual(desired, existing []corev1.PersistentVolumeClaim) bool {
+	if len(desired) != len(existing) {
+		return false
+	}
+	for i := range desired {
+		d, e := desired[i], existing[i]
+		if d.Name != e.Name ||
+			!equality.Semantic.DeepEqual(d.Spec.AccessModes, e.Spec.AccessModes) ||
+			!equality.Semantic.DeepEqual(d.Spec.Resources, e.Spec.Resources) ||
+			!equality.Semantic.DeepEqual(d.Spec.Selector, e.Spec.Selector) {
+			return false
+		}
+		if d.Spec.StorageClassName != nil && !equality.Semantic.DeepEqual(d.Spec.StorageClassName, e.Spec.StorageClassName) {
+			return false
+		}
+		if d.Spec.VolumeMode != nil && !equality.Semantic.DeepEqual(d.Spec.VolumeMode, e.Spec.VolumeMode) {
+			return false
+		}
+	}
+	return true
+}
+
+func StatefulSetAbsence(ctx context.Context, cs kubernetes.Interface, statefulSet *appsv1.StatefulSet) error {
+	return Absence(func() error {
+		propagationPolicy := metav1.DeletePropagationBackground
+		return cs.AppsV1().StatefulSets(statefulSet.Namespace).Delete(ctx, statefulSet.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagationPolicy,
+		})
+	})
+}
@@ -0,0 +1,116 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestStatefulSet() *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    int32Ptr(1),
+			ServiceName: "test",
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "test"},
+			},
+		},
+	}
+}
+
+func TestStatefulSet(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	statefulSet := newTestStatefulSet()
+	if _, outcome, err := StatefulSet(ctx, cs, statefulSet); err != nil || outcome != Created {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if _, outcome, err := StatefulSet(ctx, cs, statefulSet); err != nil || outcome != Unchanged {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+
+	statefulSet.Spec.Replicas = int32Ptr(3)
+	updated, outcome, err := StatefulSet(ctx, cs, statefulSet)
+	if err != nil || outcome != Updated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if *updated.Spec.Replicas != 3 {
+		t.Fatalf("unexpected replicas: %d", *updated.Spec.Replicas)
+	}
+
+	if err := StatefulSetAbsence(ctx, cs, statefulSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := StatefulSetAbsence(ctx, cs, statefulSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStatefulSetImmutableChange(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	statefulSet := newTestStatefulSet()
+	if _, _, err := StatefulSet(ctx, cs, statefulSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	statefulSet.Spec.ServiceName = "other"
+	statefulSet.Spec.Replicas = int32Ptr(2)
+	_, _, err := StatefulSet(ctx, cs, statefulSet)
+	var immutableErr *ImmutableFieldError
+	if !errors.As(err, &immutableErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"spec.serviceName"}; !reflect.DeepEqual(immutableErr.Fields, want) {
+		t.Fatalf("unexpected fields: %v", immutableErr.Fields)
+	}
+
+	existing, err := cs.AppsV1().StatefulSets("skop").Get(ctx, "test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if existing.Spec.ServiceName != "test" || *existing.Spec.Replicas != 1 {
+		t.Fatalf("stateful set was updated: %+v", existing.Spec)
+	}
+}
+
+func TestStatefulSetRecreate(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+
+	deleted := 0
+	cs.PrependReactor("delete", "statefulsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleted++
+		return false, nil, nil
+	})
+
+	statefulSet := newTestStatefulSet()
+	if _, _, err := StatefulSet(ctx, cs, statefulSet, RecreateOnImmutableChange()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	statefulSet.Spec.ServiceName = "other"
+	recreated, outcome, err := StatefulSet(ctx, cs, statefulSet, RecreateOnImmutableChange())
+	if err != nil || outcome != Recreated {
+		t.Fatalf("unexpected result: %v, %v", outcome, err)
+	}
+	if recreated.Spec.ServiceName != "other" {
+		t.Fatalf("unexpected service name: %q", recreated.Spec.ServiceName)
+	}
+	if deleted != 1 {
+		t.Fatalf("unexpected number of deletes: %d", deleted)
+	}
+}
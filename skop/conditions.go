@@ -0,0 +1,152 @@
+package skop
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var conditionsType = reflect.TypeOf([]metav1.Condition(nil))
+
+// statusField returns the field with the specified name of the resource's
+// Status struct. It returns an invalid value if there is no such field.
+func statusField(res Resource, name string) reflect.Value {
+	v := reflect.ValueOf(res)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	status := v.FieldByName("Status")
+	if !status.IsValid() {
+		return reflect.Value{}
+	}
+	if status.Kind() == reflect.Ptr {
+		if status.IsNil() {
+			status.Set(reflect.New(status.Type().Elem()))
+		}
+		status = status.Elem()
+	}
+	if status.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return status.FieldByName(name)
+}
+
+// statusConditions returns a pointer to the Status.Conditions field of
+// the resource, or nil if the resource does not have such a field.
+func statusConditions(res Resource) *[]metav1.Condition {
+	field := statusField(res, "Conditions")
+	if !field.IsValid() || field.Type() != conditionsType || !field.CanAddr() {
+		return nil
+	}
+	return field.Addr().Interface().(*[]metav1.Condition)
+}
+
+// setConditions sets the specified conditions on the resource's status and
+// writes the status when it changed. Resources without Status.Conditions
+// are left untouched.
+func (op *Operator) setConditions(ctx context.Context, res Resource, conditions ...metav1.Condition) error {
+	conds := statusConditions(res)
+	if conds == nil {
+		return nil
+	}
+	if !applyConditions(conds, res.GetGeneration(), conditions...) {
+		return nil
+	}
+	return op.UpdateStatus(ctx, res)
+}
+
+// applyConditions sets the specified conditions and reports whether
+// any condition changed.
+func applyConditions(conds *[]metav1.Condition, generation int64, conditions ...metav1.Condition) bool {
+	existing := append([]metav1.Condition(nil), *conds...)
+	for _, condition := range conditions {
+		if condition.ObservedGeneration == 0 {
+			condition.ObservedGeneration = generation
+		}
+		meta.SetStatusCondition(conds, condition)
+		// SetStatusCondition does not update the observed generation
+		// of an existing condition.
+		meta.FindStatusCondition(*conds, condition.Type).ObservedGeneration = condition.ObservedGeneration
+	}
+	return !equality.Semantic.DeepEqual(existing, *conds)
+}
+
+// ReadyCondition is the type of the condition set by operators
+// configured with WithManagedStatus.
+const ReadyCondition = "Ready"
+
+// WithManagedStatus configures an operator to update the status of resources
+// after every reconciler run. When the resource's Go struct has a Status
+// struct with an ObservedGeneration field of type int64, it is set to the
+// resource's generation. When the Status struct has a Conditions field of type
+// []metav1.Condition, a Ready condition reports the outcome of the reconciler
+// run. The status is only written when it changed. Updates of a resource
+// which only change its status do not trigger a reconciler run.
+func WithManagedStatus() Option {
+	return func(op *Operator) {
+		op.managedStatus = true
+	}
+}
+
+func (op *Operator) updateManagedStatus(ctx context.Context, res Resource, reconcileErr error) error {
+	changed := false
+
+	observedGeneration := statusField(res, "ObservedGeneration")
+	if observedGeneration.IsValid() && observedGeneration.Kind() == reflect.Int64 && observedGeneration.CanSet() {
+		if observedGeneration.Int() != res.GetGeneration() {
+			observedGeneration.SetInt(res.GetGeneration())
+			changed = true
+		}
+	}
+
+	if conds := statusConditions(res); conds != nil {
+		ready := metav1.Condition{
+			Type:   ReadyCondition,
+			Status: metav1.ConditionTrue,
+			Reason: "ReconcileSucceeded",
+		}
+		if reconcileErr != nil {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = "ReconcileFailed"
+			ready.Message = reconcileErr.Error()
+		}
+		if applyConditions(conds, res.GetGeneration(), ready) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return op.UpdateStatus(ctx, res)
+}
+
+// statusOnlyUpdate reports whether res differs from old only in its status
+// and in metadata which changes with every write, as is the case after the
+// status was updated.
+func statusOnlyUpdate(res, old Resource) bool {
+	if res.GetGeneration() != old.GetGeneration() {
+		return false
+	}
+	objs := make([]map[string]interface{}, 2)
+	for i, r := range []Resource{res, old} {
+		obj, err := toUnstructured(r)
+		if err != nil {
+			return false
+		}
+		delete(obj.Object, "status")
+		obj.SetResourceVersion("")
+		obj.SetManagedFields(nil)
+		objs[i] = obj.Object
+	}
+	return equality.Semantic.DeepEqual(objs[0], objs[1])
+}
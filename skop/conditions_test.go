@@ -0,0 +1,194 @@
+package skop
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+)
+
+type statusResource struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Kind              string               `json:"kind"`
+	APIVersion        string               `json:"apiVersion"`
+	Status            statusResourceStatus `json:"status"`
+}
+
+type statusResourceStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration"`
+	Conditions         []metav1.Condition `json:"conditions"`
+}
+
+func TestManagedStatus(t *testing.T) {
+	op := New(
+		WithResource("example.com", "v1", "tests", &statusResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+		WithManagedStatus(),
+	)
+
+	res := &statusResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test",
+			Namespace:  "skop",
+			Generation: 2,
+		},
+		Kind:       "Test",
+		APIVersion: "example.com/v1",
+	}
+	obj, err := toUnstructured(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), &unstructured.Unstructured{Object: obj.Object})
+	op.dynamicClient = client
+
+	ctx := context.Background()
+	if err := op.updateManagedStatus(ctx, res, errors.New("boom")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Status.ObservedGeneration != 2 {
+		t.Errorf("unexpected observed generation: %d", res.Status.ObservedGeneration)
+	}
+	ready := meta.FindStatusCondition(res.Status.Conditions, ReadyCondition)
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.Message != "boom" {
+		t.Errorf("unexpected ready condition: %+v", ready)
+	}
+
+	if err := op.updateManagedStatus(ctx, res, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(res.Status.Conditions, ReadyCondition) {
+		t.Errorf("expected ready condition to be true")
+	}
+	if n := len(client.Actions()); n != 2 {
+		t.Fatalf("unexpected number of actions: %d", n)
+	}
+
+	// Unchanged status must not be written.
+	if err := op.updateManagedStatus(ctx, res, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(client.Actions()); n != 2 {
+		t.Fatalf("unexpected number of actions: %d", n)
+	}
+
+	res.Generation = 3
+	if err := op.updateManagedStatus(ctx, res, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Status.ObservedGeneration != 3 {
+		t.Errorf("unexpected observed generation: %d", res.Status.ObservedGeneration)
+	}
+	ready = meta.FindStatusCondition(res.Status.Conditions, ReadyCondition)
+	if ready == nil || ready.ObservedGeneration != 3 {
+		t.Errorf("unexpected ready condition: %+v", ready)
+	}
+	if n := len(client.Actions()); n != 3 {
+		t.Fatalf("unexpected number of actions: %d", n)
+	}
+}
+
+func copyStatusResource(res *statusResource) *statusResource {
+	c := *res
+	res.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	return &c
+}
+
+func TestStatusOnlyUpdate(t *testing.T) {
+	old := &statusResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Generation:      1,
+			ResourceVersion: "1",
+		},
+	}
+
+	res := copyStatusResource(old)
+	res.ResourceVersion = "2"
+	res.Status.ObservedGeneration = 1
+	if !statusOnlyUpdate(res, old) {
+		t.Error("expected status-only update")
+	}
+
+	res = copyStatusResource(old)
+	res.ResourceVersion = "2"
+	res.Generation = 2
+	if statusOnlyUpdate(res, old) {
+		t.Error("expected generation change not to be a status-only update")
+	}
+
+	res = copyStatusResource(old)
+	res.ResourceVersion = "2"
+	res.Annotations = map[string]string{PausedAnnotation: "true"}
+	if statusOnlyUpdate(res, old) {
+		t.Error("expected metadata change not to be a status-only update")
+	}
+}
+
+func TestManagedStatusIgnoresStatusUpdates(t *testing.T) {
+	res := &statusResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Namespace:       "skop",
+			Generation:      1,
+			ResourceVersion: "1",
+		},
+		Kind:       "Test",
+		APIVersion: "example.com/v1",
+	}
+	obj, err := toUnstructured(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := make(chan Resource, 10)
+	op := New(
+		WithResource("example.com", "v1", "tests", &statusResource{}),
+		WithConfig(&rest.Config{}),
+		WithDynamicClient(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), obj)),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			runs <- res
+			return nil
+		})),
+		WithManagedStatus(),
+	)
+	op.informer = newTestInformer()
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+	defer func() {
+		op.Stop()
+		<-runExited
+	}()
+
+	op.updates <- update{res: copyStatusResource(res)}
+	<-runs
+
+	// The update caused by writing the status.
+	written := copyStatusResource(res)
+	written.ResourceVersion = "2"
+	written.Status.ObservedGeneration = 1
+	op.updates <- update{res: written, old: res}
+
+	// A change of the spec.
+	changed := copyStatusResource(written)
+	changed.ResourceVersion = "3"
+	changed.Generation = 2
+	op.updates <- update{res: changed, old: written}
+
+	if got := <-runs; got.GetGeneration() != 2 {
+		t.Fatalf("unexpected reconciler run for generation %d", got.GetGeneration())
+	}
+}
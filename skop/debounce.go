@@ -0,0 +1,88 @@
+package skop
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// WithDebounce configures an operator to collapse updates of a resource
+// which arrive within d of each other into a single reconciler run using
+// the latest version of the resource. A resource which keeps changing is
+// reconciled at the latest maxDelay after its first pending update. When
+// maxDelay is 0, it defaults to ten times d.
+func WithDebounce(d, maxDelay time.Duration) Option {
+	return func(op *Operator) {
+		op.debounce = d
+		op.debounceMaxDelay = maxDelay
+	}
+}
+
+type debounce struct {
+	timer   *time.Timer
+	first   time.Time
+	at      time.Time
+	trigger TriggerInfo
+}
+
+// debounceUpdate delays the reconciler run for an update of the resource
+// identified by key.
+func (op *Operator) debounceUpdate(key string, trigger TriggerInfo) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	now := time.Now()
+	pending, ok := op.debounces[key]
+	if !ok {
+		at := now.Add(op.debounce)
+		pending = &debounce{
+			first:   now,
+			at:      at,
+			trigger: trigger,
+		}
+		pending.timer = time.AfterFunc(op.debounce, func() {
+			op.fireDebounce(key, pending)
+		})
+		op.debounces[key] = pending
+		return
+	}
+
+	at := now.Add(op.debounce)
+	if deadline := pending.first.Add(op.debounceMaxDelay); at.After(deadline) {
+		at = deadline
+	}
+	if !pending.timer.Stop() {
+		// The timer already fired and the reconciler will see the
+		// latest version of the resource.
+		return
+	}
+	if pending.trigger.Reason == TriggerResync {
+		pending.trigger.Reason = trigger.Reason
+	}
+	pending.at = at
+	pending.timer.Reset(time.Until(at))
+	level.Debug(op.logger).Log(
+		"msg", "debounced update",
+		"resource", key,
+		"delay", time.Until(at),
+	)
+}
+
+func (op *Operator) fireDebounce(key string, pending *debounce) {
+	op.sendRetry(retry{
+		key:       key,
+		reason:    pending.trigger.Reason,
+		old:       pending.trigger.Old,
+		debounced: true,
+	})
+}
+
+// debounceFired removes the pending debounce for the resource identified
+// by key once its timer has fired.
+func (op *Operator) debounceFired(key string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if pending, ok := op.debounces[key]; ok && !pending.at.After(time.Now()) {
+		delete(op.debounces, key)
+	}
+}
@@ -0,0 +1,139 @@
+package skop
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+type debugState struct {
+	Resource       string          `json:"resource"`
+	LeaderElection bool            `json:"leaderElection"`
+	Leader         bool            `json:"leader"`
+	QueueLength    int             `json:"queueLength"`
+	Resources      []debugResource `json:"resources"`
+}
+
+type debugResource struct {
+	Key             string     `json:"key"`
+	Known           bool       `json:"known"`
+	Paused          bool       `json:"paused,omitempty"`
+	Stalled         bool       `json:"stalled,omitempty"`
+	Failures        uint       `json:"failures,omitempty"`
+	NextRun         *time.Time `json:"nextRun,omitempty"`
+	LastSuccessTime *time.Time `json:"lastSuccessTime,omitempty"`
+	LastError       string     `json:"lastError,omitempty"`
+	LastErrorTime   *time.Time `json:"lastErrorTime,omitempty"`
+}
+
+// DebugHandler returns an HTTP handler exposing the internal state of the
+// operator, usually mounted at /debug/skop. GET requests return the state
+// as JSON. POST requests with a key query parameter enqueue the resource
+// identified by the key.
+func (op *Operator) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(op.debugState())
+		case http.MethodPost:
+			key := r.URL.Query().Get("key")
+			if key == "" {
+				http.Error(w, "missing key", http.StatusBadRequest)
+				return
+			}
+			op.Enqueue(key)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (op *Operator) debugState() debugState {
+	var keys []string
+	if op.informer != nil {
+		keys = op.informer.Keys()
+	}
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	state := debugState{
+		Resource:    op.resource.String(),
+		Leader:      true,
+		QueueLength: int(atomic.LoadInt64(&op.queued)),
+	}
+	if op.manager != nil {
+		state.LeaderElection = op.manager.leaderElection != nil
+		state.Leader = op.manager.IsLeader()
+	}
+
+	resources := make(map[string]*debugResource)
+	get := func(key string) *debugResource {
+		r, ok := resources[key]
+		if !ok {
+			r = &debugResource{Key: key}
+			resources[key] = r
+		}
+		return r
+	}
+	for _, key := range keys {
+		get(key).Known = true
+	}
+	for key, schedule := range op.retrySchedules {
+		r := get(key)
+		r.Failures = schedule.failures
+		r.Stalled = schedule.stalled
+		if schedule.timer != nil {
+			at := schedule.at
+			r.NextRun = &at
+		}
+	}
+	for key, pending := range op.debounces {
+		r := get(key)
+		if r.NextRun == nil || pending.at.Before(*r.NextRun) {
+			at := pending.at
+			r.NextRun = &at
+		}
+	}
+	for key := range op.paused {
+		get(key).Paused = true
+	}
+	for key, result := range op.results {
+		r := get(key)
+		if !result.lastSuccess.IsZero() {
+			t := result.lastSuccess
+			r.LastSuccessTime = &t
+		}
+		if !result.lastErrorTime.IsZero() {
+			t := result.lastErrorTime
+			r.LastError = result.lastError
+			r.LastErrorTime = &t
+		}
+	}
+
+	state.Resources = make([]debugResource, 0, len(resources))
+	for _, r := range resources {
+		state.Resources = append(state.Resources, *r)
+	}
+	sort.Slice(state.Resources, func(i, j int) bool {
+		return state.Resources[i].Key < state.Resources[j].Key
+	})
+	return state
+}
+
+// forget removes the state kept for the resource identified by key
+// after the resource has been deleted.
+func (op *Operator) forget(key string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.forgetStalledLocked(key)
+	delete(op.results, key)
+	delete(op.paused, key)
+}
@@ -0,0 +1,98 @@
+package skop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestDebugHandler(t *testing.T) {
+	var (
+		results    = make(chan error)
+		reconciler = func(ctx context.Context, op *Operator, res Resource) error {
+			err := errors.New("boom")
+			if Trigger(ctx).Reason == TriggerManual {
+				err = nil
+			}
+			results <- err
+			return err
+		}
+	)
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(reconciler)),
+		WithLogger(log.NewNopLogger()),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	})
+	<-results
+
+	handler := op.DebugHandler()
+
+	// The result is recorded after the reconciler returned.
+	var state debugState
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/skop", nil))
+		state = debugState{}
+		if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+			t.Fatalf("failed to decode state: %v", err)
+		}
+		if len(state.Resources) == 1 && state.Resources[0].Failures == 1 {
+			break
+		}
+	}
+	if len(state.Resources) != 1 {
+		t.Fatalf("unexpected resources: %+v", state.Resources)
+	}
+	if r := state.Resources[0]; r.Key != "test" || !r.Known || r.Failures != 1 || r.LastError != "boom" || r.NextRun == nil {
+		t.Fatalf("unexpected resource: %+v", r)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/skop?key=test", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+	if err := <-results; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Deleted resources are no longer listed.
+	informer.delete("test")
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if state = op.debugState(); len(state.Resources) == 0 {
+			break
+		}
+	}
+	if len(state.Resources) != 0 {
+		t.Fatalf("unexpected resources: %+v", state.Resources)
+	}
+
+	op.Stop()
+	<-runExited
+}
@@ -0,0 +1,48 @@
+package skop
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const defaultEventSource = "skop"
+
+// WithEventSource configures the component name operators use
+// when recording events. Defaults to "skop".
+func WithEventSource(component string) Option {
+	return func(op *Operator) {
+		op.eventSource = component
+	}
+}
+
+func newEventRecorder(cs kubernetes.Interface, component string) (record.EventRecorder, record.EventBroadcaster) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: cs.CoreV1().Events(""),
+	})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
+		Component: component,
+	})
+	return recorder, broadcaster
+}
+
+// eventf records an event for the specified object, which is either a
+// resource or an object received from the API server.
+func (op *Operator) eventf(obj interface{}, eventtype, reason, messageFmt string, args ...interface{}) {
+	if op.recorder == nil {
+		return
+	}
+	o, ok := obj.(runtime.Object)
+	if !ok {
+		u, err := toUnstructured(obj)
+		if err != nil {
+			return
+		}
+		o = u
+	}
+	op.recorder.Eventf(o, eventtype, reason, messageFmt, args...)
+}
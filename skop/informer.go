@@ -7,7 +7,6 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -15,27 +14,24 @@ type informer interface {
 	Get(key string) Resource
 	Keys() []string
 	Key(Resource) string
-	Run(stopCh <-chan struct{}, update func(Resource))
+	Run(stopCh <-chan struct{}, update func(res, old Resource), deleted func(key string))
 }
 
 type k8sInformer struct {
 	resourceType reflect.Type
 	informer     cache.SharedIndexInformer
 	store        cache.Store
+	decodeFailed func(obj interface{}, err error)
 }
 
 func newK8sInformer(
-	config *rest.Config,
+	client dynamic.Interface,
 	namespace string,
 	defaultResync time.Duration,
 	gvr schema.GroupVersionResource,
 	resourceType reflect.Type,
-) (*k8sInformer, error) {
-	client, err := dynamic.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-
+	decodeFailed func(obj interface{}, err error),
+) *k8sInformer {
 	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, defaultResync, namespace, nil)
 	informer := factory.ForResource(gvr).Informer()
 
@@ -43,27 +39,46 @@ func newK8sInformer(
 		resourceType: resourceType,
 		informer:     informer,
 		store:        informer.GetStore(),
-	}, nil
+		decodeFailed: decodeFailed,
+	}
+}
+
+func (i *k8sInformer) Run(stopCh <-chan struct{}, update func(res, old Resource), deleted func(key string)) {
+	i.informer.AddEventHandler(i.handlers(update, deleted))
+	i.informer.Run(stopCh)
 }
 
-func (i *k8sInformer) Run(stopCh <-chan struct{}, update func(Resource)) {
-	i.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+func (i *k8sInformer) handlers(update func(res, old Resource), deleted func(key string)) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			res, err := makeResource(i.resourceType, obj)
 			if err != nil {
-				panic(err)
+				i.decodeFailed(obj, err)
+				return
 			}
-			update(res)
+			update(res, nil)
 		},
 		UpdateFunc: func(oldObj, obj interface{}) {
 			res, err := makeResource(i.resourceType, obj)
 			if err != nil {
-				panic(err)
+				i.decodeFailed(obj, err)
+				return
 			}
-			update(res)
+			// When the previous version failed to decode, the reconciler
+			// never saw it and the resource is treated as added.
+			old, _ := makeResource(i.resourceType, oldObj)
+			update(res, old)
 		},
-	})
-	i.informer.Run(stopCh)
+		DeleteFunc: func(obj interface{}) {
+			// Deleted objects are not decoded; obj may be a
+			// cache.DeletedFinalStateUnknown when the watch missed the deletion.
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				return
+			}
+			deleted(key)
+		},
+	}
 }
 
 func (i *k8sInformer) Get(key string) Resource {
@@ -76,7 +91,8 @@ func (i *k8sInformer) Get(key string) Resource {
 	}
 	res, err := makeResource(i.resourceType, obj)
 	if err != nil {
-		panic(err)
+		i.decodeFailed(obj, err)
+		return nil
 	}
 	return res
 }
@@ -0,0 +1,99 @@
+package skop
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestInformerDecodeFailed(t *testing.T) {
+	decodeErrors := generic.NewCounter("decode_errors")
+	op := New(
+		WithResource("example.com", "v1", "tests", &decodeResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+		WithLogger(log.NewNopLogger()),
+		WithMetrics(Metrics{DecodeErrors: decodeErrors}),
+	)
+	recorder := record.NewFakeRecorder(10)
+	op.recorder = recorder
+
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	i := &k8sInformer{
+		resourceType: reflect.TypeOf(decodeResource{}),
+		store:        store,
+		decodeFailed: op.decodeFailed,
+	}
+	var updates []Resource
+	handlers := i.handlers(func(res, old Resource) {
+		updates = append(updates, res)
+	}, func(key string) {})
+
+	valid := makeDecodeSource()
+	invalid := makeDecodeSource()
+	invalid.Object["spec"].(map[string]interface{})["replicas"] = "two"
+
+	handlers.OnAdd(invalid)
+	handlers.OnUpdate(valid, invalid)
+	if len(updates) != 0 {
+		t.Fatalf("unexpected updates: %+v", updates)
+	}
+	if v := decodeErrors.Value(); v != 2 {
+		t.Fatalf("unexpected decode errors: %v", v)
+	}
+	for n := 0; n < 2; n++ {
+		if event := <-recorder.Events; !strings.Contains(event, "Warning DecodeFailed") {
+			t.Fatalf("unexpected event: %s", event)
+		}
+	}
+
+	// An undecodable previous version is treated as an add.
+	handlers.OnUpdate(invalid, valid)
+	if len(updates) != 1 {
+		t.Fatalf("unexpected updates: %+v", updates)
+	}
+
+	if err := store.Add(invalid); err != nil {
+		t.Fatal(err)
+	}
+	if res := i.Get("skop/test"); res != nil {
+		t.Fatalf("unexpected resource: %+v", res)
+	}
+	if v := decodeErrors.Value(); v != 3 {
+		t.Fatalf("unexpected decode errors: %v", v)
+	}
+}
+
+func TestInformerDeleted(t *testing.T) {
+	i := &k8sInformer{
+		resourceType: reflect.TypeOf(decodeResource{}),
+		decodeFailed: func(obj interface{}, err error) {
+			t.Errorf("unexpected decode failure: %v", err)
+		},
+	}
+	var deleted []string
+	handlers := i.handlers(func(res, old Resource) {
+		t.Errorf("unexpected update: %+v", res)
+	}, func(key string) {
+		deleted = append(deleted, key)
+	})
+
+	// Deleted resources are not decoded.
+	invalid := makeDecodeSource()
+	invalid.Object["spec"].(map[string]interface{})["replicas"] = "two"
+
+	handlers.OnDelete(invalid)
+	handlers.OnDelete(cache.DeletedFinalStateUnknown{Key: "skop/other", Obj: invalid})
+	if want := []string{"skop/test", "skop/other"}; !reflect.DeepEqual(deleted, want) {
+		t.Fatalf("unexpected deleted keys: %v", deleted)
+	}
+}
@@ -0,0 +1,350 @@
+package skop
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Runnable is a component which can be run by a manager. Start must block
+// until the component has finished or ctx is cancelled.
+type Runnable interface {
+	Start(ctx context.Context) error
+}
+
+type RunnableFunc func(ctx context.Context) error
+
+func (f RunnableFunc) Start(ctx context.Context) error {
+	return f(ctx)
+}
+
+// HTTPServer returns a runnable which serves HTTP requests using the
+// specified server until the context is cancelled.
+func HTTPServer(server *http.Server) Runnable {
+	return RunnableFunc(func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- server.ListenAndServe()
+		}()
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return server.Shutdown(shutdownCtx)
+		}
+	})
+}
+
+// LeaderElectionConfig configures leader election for a manager.
+type LeaderElectionConfig struct {
+	// Config is used to create the client for managing the lease
+	// unless Clientset is set.
+	Config    *rest.Config
+	Clientset kubernetes.Interface
+
+	// Namespace and Name identify the lease object.
+	Namespace string
+	Name      string
+
+	// Identity identifies this instance. Defaults to the hostname
+	// followed by a random suffix.
+	Identity string
+
+	// LeaseDuration, RenewDeadline, and RetryPeriod default to
+	// 15, 10, and 2 seconds respectively.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Manager runs operators and other runnables with a shared lifecycle.
+// When one runnable fails, all other runnables are stopped.
+type Manager struct {
+	logger         log.Logger
+	leaderElection *LeaderElectionConfig
+	runnables      []Runnable
+	leader         int32
+	stop           chan struct{}
+	stopOnce       sync.Once
+}
+
+type ManagerOption func(m *Manager)
+
+// WithManagerLogger configures a manager to use the specified logger. This option is
+// optional and defaults to using the standard library's log package.
+func WithManagerLogger(logger log.Logger) ManagerOption {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// WithLeaderElection configures a manager to only start its runnables
+// after it acquired the lease described by config. When the lease is
+// lost, all runnables are stopped and Run returns an error.
+func WithLeaderElection(config LeaderElectionConfig) ManagerOption {
+	return func(m *Manager) {
+		m.leaderElection = &config
+	}
+}
+
+// NewManager constructs a new manager with the provided options.
+func NewManager(options ...ManagerOption) *Manager {
+	m := &Manager{
+		stop: make(chan struct{}),
+	}
+	for _, option := range options {
+		option(m)
+	}
+	if m.leaderElection != nil {
+		if m.leaderElection.Config == nil && m.leaderElection.Clientset == nil {
+			panic("skop: no leader election config configured")
+		}
+		if m.leaderElection.Name == "" || m.leaderElection.Namespace == "" {
+			panic("skop: no leader election lease configured")
+		}
+	}
+	if m.logger == nil {
+		m.logger = log.NewLogfmtLogger(log.StdlibWriter{})
+	}
+	return m
+}
+
+// Add adds a runnable to the manager. Runnables must be added
+// before calling Run.
+func (m *Manager) Add(r Runnable) {
+	m.runnables = append(m.runnables, r)
+}
+
+// IsLeader reports whether the manager currently holds the lease. Without
+// leader election, IsLeader always returns true.
+func (m *Manager) IsLeader() bool {
+	if m.leaderElection == nil {
+		return true
+	}
+	return atomic.LoadInt32(&m.leader) == 1
+}
+
+// Run starts all runnables and blocks until they have finished. Runnables
+// are stopped when the process receives SIGINT or SIGTERM, when Stop
+// is called, or when one of the runnables fails. Run returns the error
+// of the first runnable that failed.
+func (m *Manager) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case sig := <-sigCh:
+			level.Info(m.logger).Log(
+				"msg", "received signal",
+				"signal", sig,
+			)
+			cancel()
+		case <-m.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if m.leaderElection == nil {
+		return m.start(ctx)
+	}
+	return m.runLeaderElection(ctx)
+}
+
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+}
+
+type managerKey int
+
+const contextManagerKey = managerKey(0)
+
+func managerFromContext(ctx context.Context) *Manager {
+	m, _ := ctx.Value(contextManagerKey).(*Manager)
+	return m
+}
+
+func (m *Manager) start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, contextManagerKey, m))
+	defer cancel()
+
+	errCh := make(chan error, len(m.runnables))
+	var wg sync.WaitGroup
+	for _, r := range m.runnables {
+		wg.Add(1)
+		go func(r Runnable) {
+			defer wg.Done()
+			if err := r.Start(ctx); err != nil {
+				level.Error(m.logger).Log(
+					"msg", "runnable failed; stopping all runnables",
+					"err", err,
+				)
+				errCh <- err
+				cancel()
+			}
+		}(r)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+var errLeaderElectionLost = errors.New("skop: leader election lost")
+
+func (m *Manager) runLeaderElection(ctx context.Context) error {
+	config := m.leaderElection
+	cs := config.Clientset
+	if cs == nil {
+		var err error
+		cs, err = kubernetes.NewForConfig(config.Config)
+		if err != nil {
+			return err
+		}
+	}
+	identity := config.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		identity = hostname + "_" + string(uuid.NewUUID())
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lock := &observedLock{
+		Interface: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: config.Namespace,
+				Name:      config.Name,
+			},
+			Client: cs.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: identity,
+			},
+		},
+	}
+	var (
+		runErr error
+		lost   bool
+		done   = make(chan struct{})
+	)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   durationOrDefault(config.LeaseDuration, 15*time.Second),
+		RenewDeadline:   durationOrDefault(config.RenewDeadline, 10*time.Second),
+		RetryPeriod:     durationOrDefault(config.RetryPeriod, 2*time.Second),
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				defer close(done)
+				level.Info(m.logger).Log(
+					"msg", "acquired leadership",
+					"identity", identity,
+				)
+				atomic.StoreInt32(&m.leader, 1)
+				defer atomic.StoreInt32(&m.leader, 0)
+				runErr = m.start(leaderCtx)
+				switch {
+				case leaderCtx.Err() == nil:
+					// The runnables finished while still leading.
+					cancel()
+				case parent.Err() == nil:
+					lost = true
+				}
+			},
+			OnStoppedLeading: func() {
+				atomic.StoreInt32(&m.leader, 0)
+				level.Info(m.logger).Log(
+					"msg", "stopped leading",
+					"identity", identity,
+				)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	level.Info(m.logger).Log(
+		"msg", "waiting for leadership",
+		"identity", identity,
+	)
+	elector.Run(ctx)
+
+	// The elector starts OnStartedLeading in a goroutine once it acquired
+	// the lease and may return before the goroutine has started.
+	if lock.isAcquired() {
+		<-done
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if lost {
+		return errLeaderElectionLost
+	}
+	return nil
+}
+
+// observedLock records whether the lease has been acquired. The elector
+// calls OnStartedLeading exactly when it has written the lease with its
+// own identity.
+type observedLock struct {
+	resourcelock.Interface
+	acquired int32
+}
+
+func (l *observedLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Create(ctx, ler)
+	l.observe(ler, err)
+	return err
+}
+
+func (l *observedLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Update(ctx, ler)
+	l.observe(ler, err)
+	return err
+}
+
+func (l *observedLock) observe(ler resourcelock.LeaderElectionRecord, err error) {
+	if err == nil && ler.HolderIdentity == l.Identity() {
+		atomic.StoreInt32(&l.acquired, 1)
+	}
+}
+
+func (l *observedLock) isAcquired() bool {
+	return atomic.LoadInt32(&l.acquired) == 1
+}
+
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,145 @@
+package skop
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestManagerStopsRunnablesOnFailure(t *testing.T) {
+	mgr := NewManager()
+
+	stopped := make(chan struct{})
+	mgr.Add(RunnableFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	}))
+
+	boom := errors.New("boom")
+	mgr.Add(RunnableFunc(func(ctx context.Context) error {
+		return boom
+	}))
+
+	if err := mgr.Run(); err != boom {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-stopped
+}
+
+func TestManagerStop(t *testing.T) {
+	mgr := NewManager()
+
+	started := make(chan struct{})
+	mgr.Add(RunnableFunc(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	}))
+
+	runExited := make(chan error)
+	go func() {
+		runExited <- mgr.Run()
+	}()
+
+	<-started
+	mgr.Stop()
+	if err := <-runExited; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func newLeaderElectionTestManager(cs *fake.Clientset) *Manager {
+	return NewManager(WithLeaderElection(LeaderElectionConfig{
+		Clientset:     cs,
+		Namespace:     "skop",
+		Name:          "test",
+		Identity:      "test",
+		LeaseDuration: 300 * time.Millisecond,
+		RenewDeadline: 200 * time.Millisecond,
+		RetryPeriod:   50 * time.Millisecond,
+	}))
+}
+
+func TestManagerLeaderElectionLost(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	var failRenew int32
+	cs.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.LoadInt32(&failRenew) == 1 {
+			return true, nil, errors.New("renew failed")
+		}
+		return false, nil, nil
+	})
+	mgr := newLeaderElectionTestManager(cs)
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	mgr.Add(RunnableFunc(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	}))
+
+	runExited := make(chan error)
+	go func() {
+		runExited <- mgr.Run()
+	}()
+
+	<-started
+	if !mgr.IsLeader() {
+		t.Fatal("expected manager to be leader")
+	}
+	atomic.StoreInt32(&failRenew, 1)
+	if err := <-runExited; err != errLeaderElectionLost {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("runnable still running after Run returned")
+	}
+	if mgr.IsLeader() {
+		t.Fatal("expected manager not to be leader")
+	}
+}
+
+func TestManagerLeaderElectionStop(t *testing.T) {
+	mgr := newLeaderElectionTestManager(fake.NewSimpleClientset())
+
+	started := make(chan struct{})
+	mgr.Add(RunnableFunc(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	}))
+
+	runExited := make(chan error)
+	go func() {
+		runExited <- mgr.Run()
+	}()
+
+	<-started
+	mgr.Stop()
+	if err := <-runExited; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestManagerLeaderElectionRunnablesFinished(t *testing.T) {
+	mgr := newLeaderElectionTestManager(fake.NewSimpleClientset())
+
+	boom := errors.New("boom")
+	mgr.Add(RunnableFunc(func(ctx context.Context) error {
+		return boom
+	}))
+	if err := mgr.Run(); err != boom {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
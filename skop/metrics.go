@@ -0,0 +1,39 @@
+package skop
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// Metrics contains the metrics reported by an operator. Metrics which
+// are not set are discarded.
+type Metrics struct {
+	// DecodeErrors counts objects which could not be decoded into
+	// the resource's Go struct.
+	DecodeErrors metrics.Counter
+
+	// Panics counts reconciler runs which panicked.
+	Panics metrics.Counter
+
+	// Stalled is the number of resources considered stalled.
+	Stalled metrics.Gauge
+}
+
+func (m *Metrics) setDefaults() {
+	if m.DecodeErrors == nil {
+		m.DecodeErrors = discard.NewCounter()
+	}
+	if m.Panics == nil {
+		m.Panics = discard.NewCounter()
+	}
+	if m.Stalled == nil {
+		m.Stalled = discard.NewGauge()
+	}
+}
+
+// WithMetrics configures an operator to report the specified metrics.
+func WithMetrics(m Metrics) Option {
+	return func(op *Operator) {
+		op.metrics = m
+	}
+}
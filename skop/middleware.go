@@ -0,0 +1,145 @@
+package skop
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Middleware wraps a reconciler to add behavior around reconciler runs.
+type Middleware func(next Reconciler) Reconciler
+
+// WithMiddleware configures an operator to wrap its reconciler with the specified
+// middlewares. The first middleware is the outermost one. When specifying this
+// option multiple times, the middlewares are appended.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(op *Operator) {
+		op.middlewares = append(op.middlewares, middlewares...)
+	}
+}
+
+func chain(r Reconciler, middlewares []Middleware) Reconciler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		r = middlewares[i](r)
+	}
+	return r
+}
+
+// LoggingMiddleware logs the outcome of every reconciler run
+// using the context's logger.
+func LoggingMiddleware() Middleware {
+	return func(next Reconciler) Reconciler {
+		return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			start := time.Now()
+			err := next.Reconcile(ctx, op, res)
+			trigger := Trigger(ctx)
+			keyvals := []interface{}{
+				"resource", op.informer.Key(res),
+				"trigger", trigger.Reason,
+				"attempt", trigger.Attempt,
+				"duration", time.Since(start),
+			}
+			if err != nil {
+				level.Error(Logger(ctx)).Log(append([]interface{}{"msg", "reconciler failed", "err", err}, keyvals...)...)
+			} else {
+				level.Info(Logger(ctx)).Log(append([]interface{}{"msg", "reconciler succeeded"}, keyvals...)...)
+			}
+			return err
+		})
+	}
+}
+
+// LoggerMiddleware adds the resource's key to the context's logger
+// so that log lines of reconcilers can be attributed to resources.
+func LoggerMiddleware() Middleware {
+	return func(next Reconciler) Reconciler {
+		return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			logger := log.With(Logger(ctx), "resource", op.informer.Key(res))
+			return next.Reconcile(ContextWithLogger(ctx, logger), op, res)
+		})
+	}
+}
+
+// MetricsMiddleware observes the duration of every reconciler run in
+// seconds. The histogram is labeled with "trigger" and "success".
+func MetricsMiddleware(duration metrics.Histogram) Middleware {
+	return func(next Reconciler) Reconciler {
+		return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			start := time.Now()
+			err := next.Reconcile(ctx, op, res)
+			duration.With(
+				"trigger", string(Trigger(ctx).Reason),
+				"success", strconv.FormatBool(err == nil),
+			).Observe(time.Since(start).Seconds())
+			return err
+		})
+	}
+}
+
+// TimeoutMiddleware cancels the context passed to the reconciler
+// after the specified timeout.
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next Reconciler) Reconciler {
+		return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next.Reconcile(ctx, op, res)
+		})
+	}
+}
+
+// StartSpanFunc starts a tracing span for a reconciler run. It returns the
+// context passed to the reconciler and a function which finishes the span.
+type StartSpanFunc func(ctx context.Context, res Resource) (context.Context, func(err error))
+
+// TracingMiddleware wraps every reconciler run in a span started
+// by the specified function.
+func TracingMiddleware(startSpan StartSpanFunc) Middleware {
+	return func(next Reconciler) Reconciler {
+		return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			ctx, finish := startSpan(ctx, res)
+			err := next.Reconcile(ctx, op, res)
+			finish(err)
+			return err
+		})
+	}
+}
+
+// RecoverMiddleware turns panics of the reconciler into errors of type *PanicError.
+// Operators always recover from panics of reconcilers; this middleware allows
+// recovering within other middlewares.
+func RecoverMiddleware() Middleware {
+	return func(next Reconciler) Reconciler {
+		return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return callReconciler(ctx, next, op, res)
+		})
+	}
+}
+
+// PanicError is the error of a reconciler run which panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("reconciler panicked: %v", e.Value)
+}
+
+func callReconciler(ctx context.Context, r Reconciler, op *Operator, res Resource) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{
+				Value: v,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+	return r.Reconcile(ctx, op, res)
+}
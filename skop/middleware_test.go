@@ -0,0 +1,41 @@
+package skop
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	middleware := func(name string) Middleware {
+		return func(next Reconciler) Reconciler {
+			return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+				calls = append(calls, name)
+				return next.Reconcile(ctx, op, res)
+			})
+		}
+	}
+	r := chain(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+		calls = append(calls, "reconciler")
+		return nil
+	}), []Middleware{middleware("a"), middleware("b")})
+
+	if err := r.Reconcile(context.Background(), nil, &testResource{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "a,b,reconciler" {
+		t.Fatalf("unexpected calls: %s", got)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	r := RecoverMiddleware()(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+		panic("boom")
+	}))
+
+	err := r.Reconcile(context.Background(), nil, &testResource{})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
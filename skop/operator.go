@@ -2,42 +2,90 @@ package skop
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"math"
 	"reflect"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 type Operator struct {
-	namespace      string
-	defaultResync  time.Duration
-	config         *rest.Config
-	clientset      *kubernetes.Clientset
-	resource       schema.GroupVersionResource
-	resourceType   reflect.Type
-	informer       informer
-	logger         log.Logger
-	reconciler     Reconciler
-	updates        chan Resource
-	retries        chan string
-	retrySchedules map[string]retrySchedule
-	stop           chan struct{}
-	stopOnce       sync.Once
+	namespace        string
+	clusterScoped    bool
+	defaultResync    time.Duration
+	config           *rest.Config
+	clientset        kubernetes.Interface
+	dynamicClient    dynamic.Interface
+	restMapper       meta.RESTMapper
+	clientErr        error
+	resource         schema.GroupVersionResource
+	resourceType     reflect.Type
+	informer         informer
+	logger           log.Logger
+	metrics          Metrics
+	eventSource      string
+	recorder         record.EventRecorder
+	reconciler       Reconciler
+	middlewares      []Middleware
+	managedStatus    bool
+	updates          chan update
+	deletes          chan string
+	retries          chan retry
+	mu               sync.Mutex
+	retrySchedules   map[string]retrySchedule
+	debounce         time.Duration
+	debounceMaxDelay time.Duration
+	debounces        map[string]*debounce
+	paused           map[string]bool
+	stalledThreshold time.Duration
+	results          map[string]result
+	queued           int64
+	manager          *Manager
+	subscribers      map[chan Outcome]struct{}
+	stop             chan struct{}
+	stopOnce         sync.Once
+}
+
+type result struct {
+	lastSuccess   time.Time
+	lastError     string
+	lastErrorTime time.Time
+}
+
+type update struct {
+	res Resource
+	old Resource
+}
+
+type retry struct {
+	key       string
+	reason    TriggerReason
+	old       Resource
+	debounced bool
 }
 
 type retrySchedule struct {
-	timer    *time.Timer
-	failures uint
+	timer        *time.Timer
+	at           time.Time
+	failures     uint
+	firstFailure time.Time
+	stalled      bool
 }
 
 type Option func(op *Operator)
@@ -51,6 +99,14 @@ func WithNamespace(namespace string) Option {
 	}
 }
 
+// WithClusterScoped configures an operator to watch for a cluster-scoped
+// resource. It cannot be combined with WithNamespace.
+func WithClusterScoped() Option {
+	return func(op *Operator) {
+		op.clusterScoped = true
+	}
+}
+
 // WithDefaultResync configures an operator to resync after timeout is reached.
 // By default or when an a timeout of 0 is set, the operator does not resync.
 func WithDefaultResync(t time.Duration) Option {
@@ -74,13 +130,31 @@ func WithResource(group, version, resource string, prototype Resource) Option {
 }
 
 // WithConfig configures an operator to use the specified config
-// for creating Kubernetes clients.
+// for creating Kubernetes clients. The config is required unless
+// both WithClientset and WithDynamicClient are specified.
 func WithConfig(c *rest.Config) Option {
 	return func(op *Operator) {
 		op.config = c
 	}
 }
 
+// WithClientset configures an operator to use the specified clientset instead
+// of creating one from the config. This allows using fake or wrapped clients.
+func WithClientset(cs kubernetes.Interface) Option {
+	return func(op *Operator) {
+		op.clientset = cs
+	}
+}
+
+// WithDynamicClient configures an operator to use the specified dynamic client
+// for watching resources and updating their status instead of creating one
+// from the config.
+func WithDynamicClient(client dynamic.Interface) Option {
+	return func(op *Operator) {
+		op.dynamicClient = client
+	}
+}
+
 // WithLogger configures an operator to use the specified logger. This option is
 // optional and defaults to using the standard library's log package.
 func WithLogger(logger log.Logger) Option {
@@ -100,10 +174,15 @@ func WithReconciler(r Reconciler) Option {
 // New constructs a new operator with the provided options.
 func New(options ...Option) *Operator {
 	op := &Operator{
-		updates:        make(chan Resource),
-		retries:        make(chan string),
+		updates:        make(chan update),
+		deletes:        make(chan string),
+		retries:        make(chan retry),
 		stop:           make(chan struct{}),
 		retrySchedules: make(map[string]retrySchedule),
+		debounces:      make(map[string]*debounce),
+		paused:         make(map[string]bool),
+		results:        make(map[string]result),
+		subscribers:    make(map[chan Outcome]struct{}),
 	}
 	for _, option := range options {
 		option(op)
@@ -111,32 +190,65 @@ func New(options ...Option) *Operator {
 	if op.resource.Resource == "" {
 		panic("skop: no resource configured")
 	}
-	if op.config == nil {
+	if op.config == nil && (op.clientset == nil || op.dynamicClient == nil) {
 		panic("skop: no config configured")
 	}
 	if op.reconciler == nil {
 		panic("skop: no reconciler configured")
 	}
+	op.reconciler = chain(op.reconciler, op.middlewares)
+	if op.clusterScoped && op.namespace != "" {
+		panic("skop: namespace configured for cluster-scoped resource")
+	}
 	if op.logger == nil {
 		op.logger = log.NewLogfmtLogger(log.StdlibWriter{})
 	}
+	if op.debounce > 0 && op.debounceMaxDelay == 0 {
+		op.debounceMaxDelay = 10 * op.debounce
+	}
+	if op.eventSource == "" {
+		op.eventSource = defaultEventSource
+	}
+	op.metrics.setDefaults()
+	op.clientErr = op.createClients()
 	return op
 }
 
-func (op *Operator) Run() error {
-	if op.informer == nil {
-		informer, err := newK8sInformer(op.config, op.namespace, op.defaultResync, op.resource, op.resourceType)
+// createClients creates the clients which were not configured with options.
+// Errors are returned by Run.
+func (op *Operator) createClients() error {
+	if op.clientset == nil {
+		cs, err := kubernetes.NewForConfig(op.config)
+		if err != nil {
+			return err
+		}
+		op.clientset = cs
+	}
+	if op.dynamicClient == nil {
+		client, err := dynamic.NewForConfig(op.config)
 		if err != nil {
 			return err
 		}
-		op.informer = informer
+		op.dynamicClient = client
 	}
+	op.restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(op.clientset.Discovery()))
+	return nil
+}
 
-	cs, err := kubernetes.NewForConfig(op.config)
-	if err != nil {
-		return err
+func (op *Operator) Run() error {
+	if op.clientErr != nil {
+		return op.clientErr
+	}
+
+	if op.recorder == nil {
+		recorder, broadcaster := newEventRecorder(op.clientset, op.eventSource)
+		defer broadcaster.Shutdown()
+		op.recorder = recorder
+	}
+
+	if op.informer == nil {
+		op.informer = newK8sInformer(op.dynamicClient, op.namespace, op.defaultResync, op.resource, op.resourceType, op.decodeFailed)
 	}
-	op.clientset = cs
 
 	var wg sync.WaitGroup
 
@@ -156,6 +268,22 @@ func (op *Operator) Run() error {
 	return nil
 }
 
+// Start runs the operator until ctx is cancelled. It implements the
+// Runnable interface so that operators can be added to a Manager.
+func (op *Operator) Start(ctx context.Context) error {
+	op.mu.Lock()
+	op.manager = managerFromContext(ctx)
+	op.mu.Unlock()
+	go func() {
+		select {
+		case <-ctx.Done():
+			op.Stop()
+		case <-op.stop:
+		}
+	}()
+	return op.Run()
+}
+
 func (op *Operator) Stop() {
 	op.stopOnce.Do(func() {
 		close(op.stop)
@@ -164,8 +292,10 @@ func (op *Operator) Stop() {
 
 func (op *Operator) watch() {
 	level.Info(op.logger).Log("msg", "starting informer")
-	op.informer.Run(op.stop, func(res Resource) {
-		op.updates <- res
+	op.informer.Run(op.stop, func(res, old Resource) {
+		op.updates <- update{res: res, old: old}
+	}, func(key string) {
+		op.deletes <- key
 	})
 }
 
@@ -174,20 +304,55 @@ func (op *Operator) reconcile() {
 		level.Debug(op.logger).Log(
 			"msg", "waiting for update or retry",
 		)
-		var res Resource
+		var (
+			res     Resource
+			trigger TriggerInfo
+		)
 		select {
 		case <-op.stop:
 			return
-		case res = <-op.updates:
+		case key := <-op.deletes:
+			level.Debug(op.logger).Log(
+				"msg", "resource deleted",
+				"resource", key,
+			)
+			op.forget(key)
+			continue
+		case u := <-op.updates:
+			res = u.res
+			trigger = updateTrigger(u)
+			if op.managedStatus && trigger.Reason == TriggerUpdate && statusOnlyUpdate(u.res, u.old) {
+				// Writing the managed status must neither trigger another
+				// reconciler run nor pre-empt a scheduled retry.
+				level.Debug(op.logger).Log(
+					"msg", "ignoring status update",
+					"resource", res.GetName(),
+				)
+				continue
+			}
 			level.Debug(op.logger).Log(
 				"msg", "got resource from update channel",
 				"resource", res.GetName(),
+				"trigger", trigger.Reason,
 			)
-		case key := <-op.retries:
+			if op.debounce > 0 {
+				op.debounceUpdate(op.informer.Key(res), trigger)
+				continue
+			}
+		case r := <-op.retries:
+			key := r.key
+			trigger.Reason = r.reason
+			trigger.Old = r.old
 			level.Debug(op.logger).Log(
 				"msg", "got resource from retries channel",
 				"resource", key,
+				"trigger", trigger.Reason,
 			)
+			if r.debounced {
+				op.debounceFired(key)
+			} else {
+				op.retryFired(key)
+			}
 			if r := op.informer.Get(key); r != nil {
 				res = r
 			} else {
@@ -195,6 +360,7 @@ func (op *Operator) reconcile() {
 					"msg", "informer did not return resource",
 					"resource", key,
 				)
+				op.forget(key)
 			}
 		}
 		if res == nil {
@@ -206,7 +372,7 @@ func (op *Operator) reconcile() {
 			"resource", res.GetName(),
 		)
 		start := time.Now()
-		op.runReconciler(ctx, res)
+		op.runReconciler(ctx, res, trigger)
 		level.Debug(op.logger).Log(
 			"msg", "reconciler finished",
 			"resource", res.GetName(),
@@ -215,35 +381,144 @@ func (op *Operator) reconcile() {
 	}
 }
 
+func updateTrigger(u update) TriggerInfo {
+	switch {
+	case u.old == nil:
+		return TriggerInfo{Reason: TriggerAdd}
+	case u.old.GetResourceVersion() == u.res.GetResourceVersion():
+		return TriggerInfo{Reason: TriggerResync, Old: u.old}
+	default:
+		return TriggerInfo{Reason: TriggerUpdate, Old: u.old}
+	}
+}
+
+func (op *Operator) decodeFailed(obj interface{}, err error) {
+	key, _ := cache.MetaNamespaceKeyFunc(obj)
+	level.Error(op.logger).Log(
+		"msg", "failed to decode resource; skipping",
+		"resource", key,
+		"err", err,
+	)
+	op.metrics.DecodeErrors.Add(1)
+	op.eventf(obj, corev1.EventTypeWarning, "DecodeFailed", "Failed to decode resource: %v", err)
+}
+
 const maxBackoff = 5 * time.Minute
 
-func (op *Operator) runReconciler(ctx context.Context, res Resource) {
+func (op *Operator) runReconciler(ctx context.Context, res Resource, trigger TriggerInfo) {
+	key := op.informer.Key(res)
+
+	// Panics of the reconciler are recovered by callReconciler. This
+	// keeps the reconcile loop running when anything else panics.
 	defer func() {
 		if r := recover(); r != nil {
 			level.Error(op.logger).Log(
-				"msg", "reconciler panicked",
+				"msg", "reconcile loop panicked",
+				"resource", key,
 				"reason", r,
+				"stack", string(debug.Stack()),
 			)
+			op.metrics.Panics.Add(1)
 		}
 	}()
 
-	key := op.informer.Key(res)
+	if isPaused(res) {
+		op.pause(ctx, key, res)
+		return
+	}
+	op.resume(key, res)
 
-	if schedule, ok := op.retrySchedules[key]; ok {
+	op.mu.Lock()
+	schedule, ok := op.retrySchedules[key]
+	if ok && schedule.timer != nil {
 		schedule.timer.Stop()
+		schedule.timer = nil
+		op.retrySchedules[key] = schedule
 	}
+	op.mu.Unlock()
+
+	trigger.Attempt = schedule.failures
+	ctx = contextWithTrigger(ContextWithLogger(ctx, op.logger), trigger)
 
-	err := op.reconciler.Reconcile(ContextWithLogger(ctx, op.logger), op, res)
+	start := time.Now()
+	err := callReconciler(ctx, op.reconciler, op, res)
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		level.Error(op.logger).Log(
+			"msg", "reconciler panicked",
+			"resource", key,
+			"reason", panicErr.Value,
+			"stack", string(panicErr.Stack),
+		)
+		op.metrics.Panics.Add(1)
+		op.eventf(res, corev1.EventTypeWarning, "ReconcilePanicked", "Reconciler panicked: %v", panicErr.Value)
+	}
+	if op.managedStatus {
+		if statusErr := op.updateManagedStatus(ctx, res, err); statusErr != nil {
+			level.Error(op.logger).Log(
+				"msg", "failed to update status",
+				"resource", key,
+				"err", statusErr,
+			)
+			if err == nil {
+				err = statusErr
+			}
+		}
+	}
+
+	duration := time.Since(start)
+
+	if stalled, changed := op.recordResult(key, err); changed {
+		op.reportStalled(ctx, key, res, stalled, err)
+	}
+
+	op.publish(Outcome{
+		Key:      key,
+		Trigger:  trigger,
+		Attempt:  trigger.Attempt,
+		Duration: duration,
+		Err:      err,
+	})
+}
+
+// recordResult updates the retry schedule of the resource identified by
+// key after a reconciler run and reports whether the resource became
+// stalled or recovered from being stalled.
+func (op *Operator) recordResult(key string, err error) (stalled, changed bool) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	res := op.results[key]
+	if err == nil {
+		res.lastSuccess = time.Now()
+	} else {
+		res.lastError = err.Error()
+		res.lastErrorTime = time.Now()
+	}
+	op.results[key] = res
+
+	schedule := op.retrySchedules[key]
 	if err == nil {
 		level.Debug(op.logger).Log(
 			"msg", "reconciler ran without errors; removing scheduled retry",
 			"resource", key,
 		)
-		delete(op.retrySchedules, key)
-		return
+		if schedule.timer == nil {
+			delete(op.retrySchedules, key)
+		} else {
+			// The resource was enqueued while the reconciler was running.
+			op.retrySchedules[key] = retrySchedule{
+				timer: schedule.timer,
+				at:    schedule.at,
+			}
+		}
+		if schedule.stalled {
+			op.updateStalledGaugeLocked()
+		}
+		return false, schedule.stalled
 	}
 
-	failures := op.retrySchedules[key].failures
+	failures := schedule.failures
 	backoff := time.Duration(math.Pow(2, float64(failures))) * time.Second
 	if backoff > maxBackoff {
 		backoff = maxBackoff
@@ -255,17 +530,87 @@ func (op *Operator) runReconciler(ctx context.Context, res Resource) {
 		"err", err,
 	)
 
-	timer := time.AfterFunc(backoff, func() {
-		select {
-		case <-op.stop:
+	schedule.failures = failures + 1
+	if schedule.firstFailure.IsZero() {
+		schedule.firstFailure = time.Now()
+	}
+	if op.stalledThreshold > 0 && !schedule.stalled && time.Since(schedule.firstFailure) >= op.stalledThreshold {
+		schedule.stalled = true
+		changed = true
+	}
+	op.retrySchedules[key] = schedule
+	op.scheduleLocked(key, backoff, TriggerRetry)
+	if changed {
+		op.updateStalledGaugeLocked()
+	}
+	return schedule.stalled, changed
+}
+
+// scheduleLocked schedules a reconciler run for the resource identified
+// by key after d has passed, unless an earlier run is already scheduled.
+// The caller must hold op.mu.
+func (op *Operator) scheduleLocked(key string, d time.Duration, reason TriggerReason) {
+	schedule := op.retrySchedules[key]
+	at := time.Now().Add(d)
+	if schedule.timer != nil {
+		if !at.Before(schedule.at) {
 			return
-		case op.retries <- key:
 		}
+		schedule.timer.Stop()
+	}
+	schedule.timer = time.AfterFunc(d, func() {
+		op.sendRetry(retry{key: key, reason: reason})
 	})
-	op.retrySchedules[key] = retrySchedule{
-		failures: failures + 1,
-		timer:    timer,
+	schedule.at = at
+	op.retrySchedules[key] = schedule
+}
+
+// sendRetry sends r to the reconcile loop unless the operator is stopped.
+func (op *Operator) sendRetry(r retry) {
+	atomic.AddInt64(&op.queued, 1)
+	defer atomic.AddInt64(&op.queued, -1)
+	select {
+	case <-op.stop:
+	case op.retries <- r:
+	}
+}
+
+// retryFired clears the timer of the schedule for the resource identified
+// by key once it has fired.
+func (op *Operator) retryFired(key string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	schedule, ok := op.retrySchedules[key]
+	if !ok || schedule.timer == nil || schedule.at.After(time.Now()) {
+		return
 	}
+	schedule.timer = nil
+	if schedule.failures == 0 {
+		delete(op.retrySchedules, key)
+	} else {
+		op.retrySchedules[key] = schedule
+	}
+}
+
+// Enqueue schedules the resource identified by key to be reconciled
+// as soon as possible.
+func (op *Operator) Enqueue(key string) {
+	op.EnqueueAfter(key, 0)
+}
+
+// EnqueueAfter schedules the resource identified by key to be reconciled
+// after d has passed. If a retry for the resource is already scheduled
+// earlier, the resource is reconciled only once at the earlier time.
+// Pending retries keep their failure count and backoff.
+func (op *Operator) EnqueueAfter(key string, d time.Duration) {
+	op.mu.Lock()
+	op.scheduleLocked(key, d, TriggerManual)
+	op.mu.Unlock()
+	level.Debug(op.logger).Log(
+		"msg", "enqueued resource",
+		"resource", key,
+		"delay", d,
+	)
 }
 
 // Reconcile reconciles all currently known resources.
@@ -273,48 +618,52 @@ func (op *Operator) Reconcile() {
 	level.Info(op.logger).Log(
 		"msg", "reconciling all resources",
 	)
-	go func() {
-		for _, key := range op.informer.Keys() {
-			select {
-			case <-op.stop:
-				return
-			case op.retries <- key:
-				level.Debug(op.logger).Log(
-					"msg", "triggered update",
-					"resource", key,
-				)
-			}
-		}
-		level.Debug(op.logger).Log(
-			"msg", "triggered update for all resources",
-		)
-	}()
+	for _, key := range op.informer.Keys() {
+		op.Enqueue(key)
+	}
 }
 
 func (op *Operator) Config() *rest.Config {
 	return op.config
 }
 
-func (op *Operator) Clientset() *kubernetes.Clientset {
+// Clientset returns the clientset configured with WithClientset or created
+// by New. It is nil when creating the clientset failed; Run returns the error.
+func (op *Operator) Clientset() kubernetes.Interface {
 	return op.clientset
 }
 
+func (op *Operator) DynamicClient() dynamic.Interface {
+	return op.dynamicClient
+}
+
+// RESTMapper returns a mapper which resolves kinds to resources using
+// the discovery API. Discovery results are cached and refreshed when a
+// kind cannot be resolved.
+func (op *Operator) RESTMapper() meta.RESTMapper {
+	return op.restMapper
+}
+
+// UpdateStatus writes the resource's status. On success, the resource
+// version of res is updated.
 func (op *Operator) UpdateStatus(ctx context.Context, res Resource) error {
-	obj := &unstructured.Unstructured{}
-	data, err := json.Marshal(res)
+	obj, err := toUnstructured(res)
 	if err != nil {
 		return err
 	}
-	if err := json.Unmarshal(data, &obj.Object); err != nil {
-		return err
-	}
-	client, err := dynamic.NewForConfig(op.config)
+	updated, err := op.resourceClient(op.dynamicClient, res.GetNamespace()).UpdateStatus(ctx, obj, metav1.UpdateOptions{})
 	if err != nil {
 		return err
 	}
-	_, err = client.
-		Resource(op.resource).
-		Namespace(res.GetNamespace()).
-		UpdateStatus(ctx, obj, metav1.UpdateOptions{})
-	return err
+	// Keep the resource version so that the resource's status
+	// can be updated again by the same reconciler run.
+	res.SetResourceVersion(updated.GetResourceVersion())
+	return nil
+}
+
+func (op *Operator) resourceClient(client dynamic.Interface, namespace string) dynamic.ResourceInterface {
+	if op.clusterScoped {
+		return client.Resource(op.resource)
+	}
+	return client.Resource(op.resource).Namespace(namespace)
 }
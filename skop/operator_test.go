@@ -3,11 +3,19 @@ package skop
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-kit/kit/log"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 )
 
 type testResource struct {
@@ -19,12 +27,14 @@ type testResource struct {
 type testInformer struct {
 	mu        sync.Mutex
 	updates   chan Resource
+	deletes   chan string
 	resources map[string]Resource
 }
 
 func newTestInformer() *testInformer {
 	return &testInformer{
 		updates:   make(chan Resource),
+		deletes:   make(chan string),
 		resources: map[string]Resource{},
 	}
 }
@@ -49,11 +59,13 @@ func (i *testInformer) Keys() []string {
 	return all
 }
 
-func (i *testInformer) Run(stopCh <-chan struct{}, update func(Resource)) {
+func (i *testInformer) Run(stopCh <-chan struct{}, update func(res, old Resource), deleted func(key string)) {
 	for {
 		select {
 		case res := <-i.updates:
-			update(res)
+			update(res, nil)
+		case key := <-i.deletes:
+			deleted(key)
 		case <-stopCh:
 			return
 		}
@@ -67,6 +79,13 @@ func (i *testInformer) add(res Resource) {
 	i.updates <- res
 }
 
+func (i *testInformer) delete(key string) {
+	i.mu.Lock()
+	delete(i.resources, key)
+	i.mu.Unlock()
+	i.deletes <- key
+}
+
 func TestOperator(t *testing.T) {
 	var (
 		reconcilerFuncs   = make(chan func() error)
@@ -141,3 +160,597 @@ func TestOperator(t *testing.T) {
 	op.Stop()
 	<-runExited
 }
+
+func TestTrigger(t *testing.T) {
+	var (
+		triggers   = make(chan TriggerInfo)
+		boom       = errors.New("boom")
+		reconciler = func(ctx context.Context, op *Operator, res Resource) error {
+			trigger := Trigger(ctx)
+			triggers <- trigger
+			if trigger.Reason == TriggerAdd {
+				return boom
+			}
+			return nil
+		}
+	)
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(reconciler)),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	})
+
+	if trigger := <-triggers; trigger.Reason != TriggerAdd || trigger.Attempt != 0 {
+		t.Fatalf("unexpected trigger: %+v", trigger)
+	}
+	if trigger := <-triggers; trigger.Reason != TriggerRetry || trigger.Attempt != 1 {
+		t.Fatalf("unexpected trigger: %+v", trigger)
+	}
+
+	op.Reconcile()
+	if trigger := <-triggers; trigger.Reason != TriggerManual || trigger.Attempt != 0 {
+		t.Fatalf("unexpected trigger: %+v", trigger)
+	}
+
+	op.Stop()
+	<-runExited
+}
+
+func TestUpdateTrigger(t *testing.T) {
+	v1 := &testResource{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"}}
+	v2 := &testResource{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "2"}}
+
+	if trigger := updateTrigger(update{res: v1}); trigger.Reason != TriggerAdd {
+		t.Errorf("unexpected reason: %s", trigger.Reason)
+	}
+	if trigger := updateTrigger(update{res: v2, old: v1}); trigger.Reason != TriggerUpdate || trigger.Old != v1 {
+		t.Errorf("unexpected trigger: %+v", trigger)
+	}
+	if trigger := updateTrigger(update{res: v1, old: v1}); trigger.Reason != TriggerResync {
+		t.Errorf("unexpected reason: %s", trigger.Reason)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	var (
+		reconciled = make(chan string)
+		reconciler = func(ctx context.Context, op *Operator, res Resource) error {
+			reconciled <- res.GetName()
+			return nil
+		}
+	)
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(reconciler)),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	for _, name := range []string{"a", "b"} {
+		go informer.add(&testResource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "skop",
+			},
+		})
+		<-reconciled
+	}
+
+	op.Enqueue("b")
+	if name := <-reconciled; name != "b" {
+		t.Fatalf("unexpected resource reconciled: %s", name)
+	}
+
+	op.EnqueueAfter("a", 10*time.Millisecond)
+	if name := <-reconciled; name != "a" {
+		t.Fatalf("unexpected resource reconciled: %s", name)
+	}
+
+	select {
+	case name := <-reconciled:
+		t.Fatalf("unexpected resource reconciled: %s", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	op.Stop()
+	<-runExited
+}
+
+func TestDebounce(t *testing.T) {
+	var (
+		reconciled = make(chan Resource)
+		reconciler = func(ctx context.Context, op *Operator, res Resource) error {
+			reconciled <- res
+			return nil
+		}
+	)
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(reconciler)),
+		WithDebounce(50*time.Millisecond, 0),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	for generation := int64(1); generation <= 3; generation++ {
+		informer.add(&testResource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "test",
+				Namespace:  "skop",
+				Generation: generation,
+			},
+		})
+	}
+
+	if res := <-reconciled; res.GetGeneration() != 3 {
+		t.Fatalf("unexpected generation: %d", res.GetGeneration())
+	}
+
+	select {
+	case res := <-reconciled:
+		t.Fatalf("unexpected reconciler run for generation %d", res.GetGeneration())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	op.Stop()
+	<-runExited
+}
+
+func TestDebounceMaxDelay(t *testing.T) {
+	reconciled := make(chan Resource, 1)
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			select {
+			case reconciled <- res:
+			default:
+			}
+			return nil
+		})),
+		WithLogger(log.NewNopLogger()),
+		WithDebounce(100*time.Millisecond, 300*time.Millisecond),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	// Keep updating the resource faster than the debounce delay.
+	stopUpdates := make(chan struct{})
+	updatesStopped := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(updatesStopped)
+		for generation := int64(1); ; generation++ {
+			informer.add(&testResource{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "skop",
+					Generation: generation,
+				},
+			})
+			select {
+			case <-stopUpdates:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}()
+
+	select {
+	case <-reconciled:
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+			t.Fatalf("reconciled after %s, before the max delay", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("resource not reconciled within the max delay")
+	}
+
+	close(stopUpdates)
+	<-updatesStopped
+	op.Stop()
+	<-runExited
+}
+
+func TestPanic(t *testing.T) {
+	var (
+		triggers   = make(chan TriggerInfo)
+		reconciler = func(ctx context.Context, op *Operator, res Resource) error {
+			trigger := Trigger(ctx)
+			triggers <- trigger
+			if trigger.Attempt == 0 {
+				panic("boom")
+			}
+			return nil
+		}
+	)
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(reconciler)),
+		WithLogger(log.NewNopLogger()),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+	recorder := record.NewFakeRecorder(10)
+	op.recorder = recorder
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	})
+
+	<-triggers
+	if event := <-recorder.Events; !strings.Contains(event, "ReconcilePanicked") {
+		t.Fatalf("unexpected event: %s", event)
+	}
+
+	// The panic must have scheduled a retry.
+	if trigger := <-triggers; trigger.Reason != TriggerRetry || trigger.Attempt != 1 {
+		t.Fatalf("unexpected trigger: %+v", trigger)
+	}
+
+	op.Stop()
+	<-runExited
+}
+
+// panicRecorder is an event recorder which panics when recording an event.
+type panicRecorder struct {
+	*record.FakeRecorder
+}
+
+func (r panicRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	panic("recorder panicked")
+}
+
+func TestPanicOutsideReconciler(t *testing.T) {
+	reconciled := make(chan Resource)
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			reconciled <- res
+			return nil
+		})),
+		WithLogger(log.NewNopLogger()),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+	op.recorder = panicRecorder{record.NewFakeRecorder(10)}
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	// Pausing records an event, which panics.
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "paused",
+			Namespace:   "skop",
+			Annotations: map[string]string{PausedAnnotation: "true"},
+		},
+	})
+
+	// The reconcile loop must still be running.
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	})
+	if res := <-reconciled; res.GetName() != "test" {
+		t.Fatalf("unexpected resource: %s", res.GetName())
+	}
+
+	op.Stop()
+	<-runExited
+}
+
+func TestPause(t *testing.T) {
+	var (
+		reconciled = make(chan Resource)
+		reconciler = func(ctx context.Context, op *Operator, res Resource) error {
+			reconciled <- res
+			return nil
+		}
+	)
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(reconciler)),
+		WithLogger(log.NewNopLogger()),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+	recorder := record.NewFakeRecorder(10)
+	op.recorder = recorder
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+			Annotations: map[string]string{
+				PausedAnnotation: "true",
+			},
+		},
+	})
+	if event := <-recorder.Events; !strings.Contains(event, "ReconcilePaused") {
+		t.Fatalf("unexpected event: %s", event)
+	}
+
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	})
+	if event := <-recorder.Events; !strings.Contains(event, "ReconcileResumed") {
+		t.Fatalf("unexpected event: %s", event)
+	}
+	<-reconciled
+
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+			Annotations: map[string]string{
+				PausedAnnotation: "true",
+			},
+		},
+	})
+	if event := <-recorder.Events; !strings.Contains(event, "ReconcilePaused") {
+		t.Fatalf("unexpected event: %s", event)
+	}
+
+	// Deleted resources are no longer tracked as paused. Updates are
+	// processed in order, so the deletion was handled once the other
+	// resource was reconciled.
+	informer.delete("test")
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other",
+			Namespace: "skop",
+		},
+	})
+	<-reconciled
+	op.mu.Lock()
+	paused := op.paused["test"]
+	op.mu.Unlock()
+	if paused {
+		t.Error("expected deleted resource to be forgotten")
+	}
+
+	op.Stop()
+	<-runExited
+}
+
+func TestSubscribe(t *testing.T) {
+	boom := errors.New("boom")
+	reconciler := func(ctx context.Context, op *Operator, res Resource) error {
+		if Trigger(ctx).Reason == TriggerAdd {
+			return boom
+		}
+		return nil
+	}
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(reconciler)),
+		WithLogger(log.NewNopLogger()),
+	)
+
+	informer := newTestInformer()
+	op.informer = informer
+
+	outcomes, cancel := op.Subscribe()
+
+	// A subscriber which never receives must not block the operator.
+	_, cancelStale := op.Subscribe()
+	defer cancelStale()
+
+	runExited := make(chan struct{})
+	go func() {
+		op.Run()
+		close(runExited)
+	}()
+
+	informer.add(&testResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "skop",
+		},
+	})
+
+	if outcome := <-outcomes; outcome.Key != "test" || outcome.Err != boom || outcome.Trigger.Reason != TriggerAdd {
+		t.Fatalf("unexpected outcome: %+v", outcome)
+	}
+	if outcome := <-outcomes; outcome.Err != nil || outcome.Trigger.Reason != TriggerRetry || outcome.Attempt != 1 {
+		t.Fatalf("unexpected outcome: %+v", outcome)
+	}
+
+	cancel()
+	if _, ok := <-outcomes; ok {
+		t.Fatal("expected channel to be closed")
+	}
+
+	op.Stop()
+	<-runExited
+}
+
+func TestClusterScopedWithNamespace(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "skop: namespace configured for cluster-scoped resource" {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithClusterScoped(),
+		WithNamespace("skop"),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+	)
+}
+
+// namespaceRecordingClient records whether a namespaced
+// resource client was requested.
+type namespaceRecordingClient struct {
+	dynamic.Interface
+	namespaced bool
+}
+
+func (c *namespaceRecordingClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &namespaceRecordingResource{c.Interface.Resource(resource), c}
+}
+
+type namespaceRecordingResource struct {
+	dynamic.NamespaceableResourceInterface
+	client *namespaceRecordingClient
+}
+
+func (r *namespaceRecordingResource) Namespace(namespace string) dynamic.ResourceInterface {
+	r.client.namespaced = true
+	return r.NamespaceableResourceInterface.Namespace(namespace)
+}
+
+func TestClusterScopedUpdateStatus(t *testing.T) {
+	res := &statusResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Kind:       "Test",
+		APIVersion: "example.com/v1",
+	}
+	obj, err := toUnstructured(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &namespaceRecordingClient{
+		Interface: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), obj),
+	}
+
+	op := New(
+		WithResource("example.com", "v1", "tests", &statusResource{}),
+		WithConfig(&rest.Config{}),
+		WithClusterScoped(),
+		WithDynamicClient(client),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+	)
+
+	res.Status.ObservedGeneration = 1
+	if err := op.UpdateStatus(context.Background(), res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.namespaced {
+		t.Fatal("status of cluster-scoped resource updated using namespaced client")
+	}
+	actions := client.Interface.(*dynamicfake.FakeDynamicClient).Actions()
+	if len(actions) != 1 {
+		t.Fatalf("unexpected number of actions: %d", len(actions))
+	}
+	if action := actions[0]; action.GetSubresource() != "status" || action.GetNamespace() != "" {
+		t.Fatalf("unexpected action: %+v", action)
+	}
+}
+
+func TestRunClientError(t *testing.T) {
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{
+			Host: "https://localhost",
+			TLSClientConfig: rest.TLSClientConfig{
+				CAFile: "testdata/does-not-exist",
+			},
+		}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+		WithLogger(log.NewNopLogger()),
+	)
+	if op.Clientset() != nil {
+		t.Fatal("expected no clientset")
+	}
+	if err := op.Run(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNewClients(t *testing.T) {
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+	)
+	if op.Clientset() == nil || op.DynamicClient() == nil || op.RESTMapper() == nil {
+		t.Fatal("expected clients to be created by New")
+	}
+}
@@ -0,0 +1,78 @@
+package skop
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PausedAnnotation is the annotation which pauses reconciling a resource
+// when set to "true". Pending retries of paused resources are cleared.
+const PausedAnnotation = "skop.io/paused"
+
+func isPaused(res Resource) bool {
+	return res.GetAnnotations()[PausedAnnotation] == "true"
+}
+
+// pause clears pending retries of the paused resource identified by key
+// and reports that the resource is paused when it was not paused before.
+func (op *Operator) pause(ctx context.Context, key string, res Resource) {
+	op.mu.Lock()
+	if schedule, ok := op.retrySchedules[key]; ok && schedule.timer != nil {
+		schedule.timer.Stop()
+	}
+	delete(op.retrySchedules, key)
+	op.updateStalledGaugeLocked()
+	wasPaused := op.paused[key]
+	op.paused[key] = true
+	op.mu.Unlock()
+
+	if wasPaused {
+		level.Debug(op.logger).Log(
+			"msg", "reconciliation is paused; skipping resource",
+			"resource", key,
+		)
+		return
+	}
+
+	level.Info(op.logger).Log(
+		"msg", "reconciliation paused",
+		"resource", key,
+	)
+	op.eventf(res, corev1.EventTypeNormal, "ReconcilePaused", "Reconciliation is paused by annotation %s", PausedAnnotation)
+
+	if op.managedStatus {
+		err := op.setConditions(ctx, res, metav1.Condition{
+			Type:    ReadyCondition,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "ReconcilePaused",
+			Message: "Reconciliation is paused by annotation " + PausedAnnotation,
+		})
+		if err != nil {
+			level.Error(op.logger).Log(
+				"msg", "failed to update status",
+				"resource", key,
+				"err", err,
+			)
+		}
+	}
+}
+
+// resume reports that the resource identified by key is no longer paused.
+func (op *Operator) resume(key string, res Resource) {
+	op.mu.Lock()
+	wasPaused := op.paused[key]
+	delete(op.paused, key)
+	op.mu.Unlock()
+
+	if !wasPaused {
+		return
+	}
+	level.Info(op.logger).Log(
+		"msg", "reconciliation resumed",
+		"resource", key,
+	)
+	op.eventf(res, corev1.EventTypeNormal, "ReconcileResumed", "Reconciliation resumed")
+}
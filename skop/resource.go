@@ -5,6 +5,8 @@ import (
 	"reflect"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type Resource interface {
@@ -13,6 +15,12 @@ type Resource interface {
 
 func makeResource(resourceType reflect.Type, source interface{}) (Resource, error) {
 	dest := reflect.New(resourceType).Interface().(Resource)
+	if u, ok := source.(*unstructured.Unstructured); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, dest); err != nil {
+			return nil, err
+		}
+		return dest, nil
+	}
 	data, err := json.Marshal(source)
 	if err != nil {
 		return nil, err
@@ -22,3 +30,11 @@ func makeResource(resourceType reflect.Type, source interface{}) (Resource, erro
 	}
 	return dest, nil
 }
+
+func toUnstructured(obj interface{}) (*unstructured.Unstructured, error) {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: data}, nil
+}
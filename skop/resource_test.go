@@ -0,0 +1,110 @@
+package skop
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type decodeResource struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Kind              string             `json:"kind"`
+	APIVersion        string             `json:"apiVersion"`
+	Spec              decodeResourceSpec `json:"spec"`
+}
+
+type decodeResourceSpec struct {
+	Replicas int               `json:"replicas"`
+	Image    string            `json:"image"`
+	Args     []string          `json:"args"`
+	Env      map[string]string `json:"env"`
+}
+
+func makeDecodeSource() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Test",
+		"metadata": map[string]interface{}{
+			"name":              "test",
+			"namespace":         "skop",
+			"generation":        int64(3),
+			"resourceVersion":   "42",
+			"creationTimestamp": "2020-09-01T12:00:00Z",
+			"labels": map[string]interface{}{
+				"app": "test",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"image":    "alpine:3.10",
+			"args":     []interface{}{"sh", "-c", "sleep 1"},
+			"env": map[string]interface{}{
+				"FOO": "bar",
+			},
+		},
+	}}
+}
+
+func TestMakeResource(t *testing.T) {
+	resourceType := reflect.TypeOf(decodeResource{})
+
+	res, err := makeResource(resourceType, makeDecodeSource())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := makeResourceJSON(resourceType, makeDecodeSource())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Fatalf("unexpected resource:\ngot:  %+v\nwant: %+v", res, want)
+	}
+}
+
+func TestMakeResourceInvalid(t *testing.T) {
+	source := makeDecodeSource()
+	source.Object["spec"].(map[string]interface{})["replicas"] = "two"
+
+	if _, err := makeResource(reflect.TypeOf(decodeResource{}), source); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func BenchmarkMakeResource(b *testing.B) {
+	resourceType := reflect.TypeOf(decodeResource{})
+	source := makeDecodeSource()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := makeResource(resourceType, source); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMakeResourceJSON measures decoding using a JSON round-trip
+// for comparison with BenchmarkMakeResource.
+func BenchmarkMakeResourceJSON(b *testing.B) {
+	resourceType := reflect.TypeOf(decodeResource{})
+	source := makeDecodeSource()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := makeResourceJSON(resourceType, source); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func makeResourceJSON(resourceType reflect.Type, source interface{}) (Resource, error) {
+	dest := reflect.New(resourceType).Interface().(Resource)
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &dest); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
@@ -0,0 +1,87 @@
+package skop
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StalledCondition is the type of the condition set for stalled resources
+// by operators configured with WithManagedStatus.
+const StalledCondition = "Stalled"
+
+// WithStalledThreshold configures an operator to consider a resource stalled
+// when its reconciler keeps failing for longer than the specified threshold.
+// Stalled resources are reported with a Warning event, a Stalled condition
+// if the operator manages the status, and the Stalled metric.
+func WithStalledThreshold(threshold time.Duration) Option {
+	return func(op *Operator) {
+		op.stalledThreshold = threshold
+	}
+}
+
+func (op *Operator) reportStalled(ctx context.Context, key string, res Resource, stalled bool, err error) {
+	condition := metav1.Condition{
+		Type:   StalledCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "ReconcileSucceeded",
+	}
+	if stalled {
+		level.Warn(op.logger).Log(
+			"msg", "resource is stalled",
+			"resource", key,
+			"threshold", op.stalledThreshold,
+			"err", err,
+		)
+		op.eventf(res, corev1.EventTypeWarning, "Stalled", "Reconciler kept failing for more than %s: %v", op.stalledThreshold, err)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ReconcileFailing"
+		condition.Message = err.Error()
+	} else {
+		level.Info(op.logger).Log(
+			"msg", "resource is no longer stalled",
+			"resource", key,
+		)
+	}
+
+	if !op.managedStatus {
+		return
+	}
+	if err := op.setConditions(ctx, res, condition); err != nil {
+		level.Error(op.logger).Log(
+			"msg", "failed to update status",
+			"resource", key,
+			"err", err,
+		)
+	}
+}
+
+// updateStalledGaugeLocked updates the Stalled metric.
+// The caller must hold op.mu.
+func (op *Operator) updateStalledGaugeLocked() {
+	n := 0
+	for _, schedule := range op.retrySchedules {
+		if schedule.stalled {
+			n++
+		}
+	}
+	op.metrics.Stalled.Set(float64(n))
+}
+
+// forgetStalledLocked removes the retry schedule of the deleted resource
+// identified by key, so that it no longer counts as stalled. A pending
+// retry is kept; it forgets the resource once it fires.
+// The caller must hold op.mu.
+func (op *Operator) forgetStalledLocked(key string) {
+	schedule, ok := op.retrySchedules[key]
+	if !ok || schedule.timer != nil {
+		return
+	}
+	delete(op.retrySchedules, key)
+	if schedule.stalled {
+		op.updateStalledGaugeLocked()
+	}
+}
@@ -0,0 +1,90 @@
+package skop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
+	"k8s.io/client-go/rest"
+)
+
+func TestStalled(t *testing.T) {
+	stalledGauge := generic.NewGauge("stalled")
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+		WithLogger(log.NewNopLogger()),
+		WithStalledThreshold(time.Minute),
+		WithMetrics(Metrics{Stalled: stalledGauge}),
+	)
+	defer op.Stop()
+
+	boom := errors.New("boom")
+	if stalled, changed := op.recordResult("test", boom); stalled || changed {
+		t.Fatal("resource must not be stalled after first failure")
+	}
+
+	op.mu.Lock()
+	schedule := op.retrySchedules["test"]
+	schedule.firstFailure = time.Now().Add(-2 * time.Minute)
+	op.retrySchedules["test"] = schedule
+	op.mu.Unlock()
+
+	if stalled, changed := op.recordResult("test", boom); !stalled || !changed {
+		t.Fatal("resource must be stalled")
+	}
+	if v := stalledGauge.Value(); v != 1 {
+		t.Fatalf("unexpected stalled gauge value: %v", v)
+	}
+	if stalled, changed := op.recordResult("test", boom); !stalled || changed {
+		t.Fatal("resource must stay stalled")
+	}
+
+	if stalled, changed := op.recordResult("test", nil); stalled || !changed {
+		t.Fatal("resource must have recovered")
+	}
+	if v := stalledGauge.Value(); v != 0 {
+		t.Fatalf("unexpected stalled gauge value: %v", v)
+	}
+}
+
+func TestStalledResourceDeleted(t *testing.T) {
+	stalledGauge := generic.NewGauge("stalled")
+	op := New(
+		WithResource("example.com", "v1", "tests", &testResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+		WithLogger(log.NewNopLogger()),
+		WithStalledThreshold(time.Minute),
+		WithMetrics(Metrics{Stalled: stalledGauge}),
+	)
+	defer op.Stop()
+
+	op.mu.Lock()
+	op.retrySchedules["test"] = retrySchedule{
+		failures:     5,
+		firstFailure: time.Now().Add(-2 * time.Minute),
+		stalled:      true,
+	}
+	op.updateStalledGaugeLocked()
+	op.mu.Unlock()
+	if v := stalledGauge.Value(); v != 1 {
+		t.Fatalf("unexpected stalled gauge value: %v", v)
+	}
+
+	// The retry fired and the informer no longer knows the resource.
+	op.mu.Lock()
+	op.forgetStalledLocked("test")
+	op.mu.Unlock()
+	if v := stalledGauge.Value(); v != 0 {
+		t.Fatalf("unexpected stalled gauge value: %v", v)
+	}
+}
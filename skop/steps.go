@@ -0,0 +1,161 @@
+package skop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Step is a named part of a reconciler.
+type Step struct {
+	Name       string
+	Reconciler Reconciler
+
+	// Condition is the type of the condition reporting the outcome of the
+	// step in the resource's status. It is only set when the resource's
+	// Go struct has a Status.Conditions field of type []metav1.Condition.
+	// When empty, no condition is set.
+	Condition string
+}
+
+// StepError is returned by Sequence when a step failed.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// StepErrors is returned by Parallel when one or more steps failed.
+type StepErrors []*StepError
+
+func (e StepErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Sequence returns a reconciler which runs the specified steps one after
+// another. It stops at the first step which fails and returns a *StepError.
+func Sequence(steps ...Step) Reconciler {
+	return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+		var (
+			stepErr    error
+			conditions []metav1.Condition
+		)
+		for _, step := range steps {
+			err := runStep(ctx, op, res, step)
+			if step.Condition != "" {
+				conditions = append(conditions, stepCondition(step, err))
+			}
+			if err != nil {
+				stepErr = &StepError{Step: step.Name, Err: err}
+				break
+			}
+		}
+		return setStepConditions(ctx, op, res, stepErr, conditions)
+	})
+}
+
+// Parallel returns a reconciler which runs the specified steps concurrently
+// and waits for all of them to finish. When steps fail, it returns StepErrors.
+// Steps running in parallel must not modify the resource.
+func Parallel(steps ...Step) Reconciler {
+	return ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+		errs := make([]error, len(steps))
+		var wg sync.WaitGroup
+		for i, step := range steps {
+			wg.Add(1)
+			go func(i int, step Step) {
+				defer wg.Done()
+				errs[i] = runStep(ctx, op, res, step)
+			}(i, step)
+		}
+		wg.Wait()
+
+		var (
+			stepErrs   StepErrors
+			conditions []metav1.Condition
+		)
+		for i, step := range steps {
+			if step.Condition != "" {
+				conditions = append(conditions, stepCondition(step, errs[i]))
+			}
+			if errs[i] != nil {
+				stepErrs = append(stepErrs, &StepError{Step: step.Name, Err: errs[i]})
+			}
+		}
+		if len(stepErrs) == 0 {
+			return setStepConditions(ctx, op, res, nil, conditions)
+		}
+		return setStepConditions(ctx, op, res, stepErrs, conditions)
+	})
+}
+
+func runStep(ctx context.Context, op *Operator, res Resource, step Step) error {
+	logger := log.With(Logger(ctx), "step", step.Name)
+	start := time.Now()
+	err := step.Reconciler.Reconcile(ContextWithLogger(ctx, logger), op, res)
+	if err != nil {
+		level.Error(logger).Log(
+			"msg", "step failed",
+			"duration", time.Since(start),
+			"err", err,
+		)
+	} else {
+		level.Debug(logger).Log(
+			"msg", "step succeeded",
+			"duration", time.Since(start),
+		)
+	}
+	return err
+}
+
+func stepCondition(step Step, err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:    step.Condition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "StepFailed",
+			Message: err.Error(),
+		}
+	}
+	return metav1.Condition{
+		Type:   step.Condition,
+		Status: metav1.ConditionTrue,
+		Reason: "StepSucceeded",
+	}
+}
+
+// setStepConditions writes the conditions of the steps and returns stepErr
+// if it is not nil, or the error writing the conditions otherwise.
+func setStepConditions(ctx context.Context, op *Operator, res Resource, stepErr error, conditions []metav1.Condition) error {
+	if len(conditions) == 0 {
+		return stepErr
+	}
+	if err := op.setConditions(ctx, res, conditions...); err != nil {
+		if stepErr != nil {
+			level.Error(Logger(ctx)).Log(
+				"msg", "failed to set step conditions",
+				"err", err,
+			)
+			return stepErr
+		}
+		return err
+	}
+	return stepErr
+}
@@ -0,0 +1,172 @@
+package skop
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestSequence(t *testing.T) {
+	var calls []string
+	step := func(name string, err error) Step {
+		return Step{
+			Name: name,
+			Reconciler: ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+				calls = append(calls, name)
+				return err
+			}),
+		}
+	}
+
+	boom := errors.New("boom")
+	r := Sequence(step("a", nil), step("b", boom), step("c", nil))
+
+	ctx := ContextWithLogger(context.Background(), log.NewNopLogger())
+	err := r.Reconcile(ctx, nil, &testResource{})
+
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != "b" || !errors.Is(err, boom) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	var calls int32
+	step := func(name string, err error) Step {
+		return Step{
+			Name: name,
+			Reconciler: ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+				atomic.AddInt32(&calls, 1)
+				return err
+			}),
+		}
+	}
+
+	boom := errors.New("boom")
+	r := Parallel(step("a", boom), step("b", nil), step("c", boom))
+
+	ctx := ContextWithLogger(context.Background(), log.NewNopLogger())
+	err := r.Reconcile(ctx, nil, &testResource{})
+
+	var stepErrs StepErrors
+	if !errors.As(err, &stepErrs) || len(stepErrs) != 2 || stepErrs[0].Step != "a" || stepErrs[1].Step != "c" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("unexpected number of calls: %d", calls)
+	}
+}
+
+type conditionsResource struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Status            struct {
+		Conditions []metav1.Condition `json:"conditions"`
+	} `json:"status"`
+}
+
+func TestStatusConditions(t *testing.T) {
+	if conds := statusConditions(&testResource{}); conds != nil {
+		t.Fatal("expected no conditions")
+	}
+
+	res := &conditionsResource{}
+	conds := statusConditions(res)
+	if conds == nil {
+		t.Fatal("expected conditions")
+	}
+	*conds = append(*conds, metav1.Condition{Type: "Ready"})
+	if len(res.Status.Conditions) != 1 {
+		t.Fatal("conditions not set")
+	}
+}
+
+func TestStepConditions(t *testing.T) {
+	op := New(
+		WithResource("example.com", "v1", "tests", &statusResource{}),
+		WithConfig(&rest.Config{}),
+		WithReconciler(ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+			return nil
+		})),
+	)
+
+	res := &statusResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test",
+			Namespace:  "skop",
+			Generation: 1,
+		},
+		Kind:       "Test",
+		APIVersion: "example.com/v1",
+	}
+	obj, err := toUnstructured(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), &unstructured.Unstructured{Object: obj.Object})
+	op.dynamicClient = client
+
+	boom := errors.New("boom")
+	step := func(name string, err error) Step {
+		return Step{
+			Name:      name,
+			Condition: name,
+			Reconciler: ReconcilerFunc(func(ctx context.Context, op *Operator, res Resource) error {
+				return err
+			}),
+		}
+	}
+	ctx := ContextWithLogger(context.Background(), log.NewNopLogger())
+
+	r := Sequence(step("A", nil), step("B", boom), step("C", nil))
+	if err := r.Reconcile(ctx, op, res); !errors.Is(err, boom) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(res.Status.Conditions, "A") {
+		t.Error("expected condition A to be true")
+	}
+	b := meta.FindStatusCondition(res.Status.Conditions, "B")
+	if b == nil || b.Status != metav1.ConditionFalse || b.Message != "boom" {
+		t.Errorf("unexpected condition B: %+v", b)
+	}
+	if meta.FindStatusCondition(res.Status.Conditions, "C") != nil {
+		t.Error("expected no condition C")
+	}
+	if n := len(client.Actions()); n != 1 {
+		t.Fatalf("unexpected number of actions: %d", n)
+	}
+
+	// Unchanged conditions must not be written.
+	if err := r.Reconcile(ctx, op, res); !errors.Is(err, boom) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(client.Actions()); n != 1 {
+		t.Fatalf("unexpected number of actions: %d", n)
+	}
+
+	res.Generation = 2
+	r = Parallel(step("A", nil), step("B", nil), step("C", nil))
+	if err := r.Reconcile(ctx, op, res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, typ := range []string{"A", "B", "C"} {
+		cond := meta.FindStatusCondition(res.Status.Conditions, typ)
+		if cond == nil || cond.Status != metav1.ConditionTrue || cond.ObservedGeneration != 2 {
+			t.Errorf("unexpected condition %s: %+v", typ, cond)
+		}
+	}
+	if n := len(client.Actions()); n != 2 {
+		t.Fatalf("unexpected number of actions: %d", n)
+	}
+}
@@ -0,0 +1,47 @@
+package skop
+
+import "time"
+
+// subscriptionBuffer is the number of outcomes buffered per subscriber.
+const subscriptionBuffer = 100
+
+// Outcome describes a finished reconciler run.
+type Outcome struct {
+	Key      string
+	Trigger  TriggerInfo
+	Attempt  uint
+	Duration time.Duration
+	Err      error
+}
+
+// Subscribe returns a channel receiving the outcome of every reconciler run
+// and a function which cancels the subscription and closes the channel.
+// Outcomes are dropped for subscribers which fall behind so that they
+// never block the operator.
+func (op *Operator) Subscribe() (<-chan Outcome, func()) {
+	ch := make(chan Outcome, subscriptionBuffer)
+	op.mu.Lock()
+	op.subscribers[ch] = struct{}{}
+	op.mu.Unlock()
+
+	cancel := func() {
+		op.mu.Lock()
+		defer op.mu.Unlock()
+		if _, ok := op.subscribers[ch]; ok {
+			delete(op.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+func (op *Operator) publish(outcome Outcome) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	for ch := range op.subscribers {
+		select {
+		case ch <- outcome:
+		default:
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package skop
+
+import "context"
+
+// TriggerReason describes why a reconciler was called.
+type TriggerReason string
+
+const (
+	// TriggerAdd means the resource was added.
+	TriggerAdd TriggerReason = "add"
+	// TriggerUpdate means the resource was updated.
+	TriggerUpdate TriggerReason = "update"
+	// TriggerResync means the informer resynced an unchanged resource.
+	TriggerResync TriggerReason = "resync"
+	// TriggerRetry means a previous reconciler run failed.
+	TriggerRetry TriggerReason = "retry"
+	// TriggerManual means the resource was reconciled on request, for
+	// example by calling Operator.Reconcile.
+	TriggerManual TriggerReason = "manual"
+)
+
+// TriggerInfo describes why a reconciler was called.
+type TriggerInfo struct {
+	Reason TriggerReason
+
+	// Attempt is the number of consecutive failed reconciler
+	// runs preceding this one.
+	Attempt uint
+
+	// Old is the previous version of the resource. It is only
+	// set for updates and resyncs.
+	Old Resource
+}
+
+type triggerKey int
+
+const contextTriggerKey = triggerKey(0)
+
+// Trigger returns information about why the reconciler was called.
+func Trigger(ctx context.Context) TriggerInfo {
+	trigger, _ := ctx.Value(contextTriggerKey).(TriggerInfo)
+	return trigger
+}
+
+func contextWithTrigger(ctx context.Context, trigger TriggerInfo) context.Context {
+	return context.WithValue(ctx, contextTriggerKey, trigger)
+}